@@ -0,0 +1,158 @@
+package ctfdsetup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// rotateAdmin patches the administrator email and password to the rotated ones,
+// then checks logging in with the new credentials works before reporting success.
+// Credentials already rotated (e.g. by a previous run) are left untouched.
+func rotateAdmin(ctx context.Context, client *Client, admin Admin, opts ...Option) error {
+	rot := admin.Rotate
+	if rot == nil || (rot.Email == nil && rot.Password == nil) {
+		return nil
+	}
+	name := admin.Name.Content
+
+	ctfdU, err := findAdmin(ctx, client, name, opts...)
+	if err != nil {
+		return err
+	}
+	if ctfdU == nil {
+		return fmt.Errorf("administrator %s not found", name)
+	}
+
+	if adminRotated(ctx, client.url, ctfdU, admin, opts...) {
+		Log().Info(ctx, "administrator credentials are up to date")
+		return nil
+	}
+
+	Log().Info(ctx, "rotating administrator credentials",
+		zap.String("name", name),
+		zap.Bool("email", rot.Email != nil),
+		zap.Bool("password", rot.Password != nil),
+	)
+	params := &api.PatchUsersParams{
+		Name:     ctfdU.Name,
+		Email:    deref(ctfdU.Email),
+		Password: rot.Password.ptr(),
+		Fields:   []api.Field{},
+	}
+	if rot.Email != nil {
+		params.Email = rot.Email.Content
+	}
+	if _, err := client.PatchUser(ctx, ctfdU.ID, params, opts...); err != nil {
+		return &ErrClient{err: err}
+	}
+
+	// Check the new credentials before reporting success
+	if _, err := adminLogin(ctx, client.url, name, rotatedPassword(admin), opts...); err != nil {
+		return errors.Wrap(err, "logging in with the rotated credentials")
+	}
+	Log().Info(ctx, "administrator credentials rotated",
+		zap.String("name", name),
+	)
+	return nil
+}
+
+// diffAdmin compares the CTFd administrator to the rotated credentials.
+// The password can't be read, thus it is reported as changed if logging
+// in with it fails.
+func diffAdmin(ctx context.Context, client *Client, bare bool, admin Admin, opts ...Option) ([]*Diff, error) {
+	rot := admin.Rotate
+	if bare || rot == nil || (rot.Email == nil && rot.Password == nil) {
+		return nil, nil
+	}
+
+	ctfdU, err := findAdmin(ctx, client, admin.Name.Content, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if ctfdU == nil {
+		return nil, fmt.Errorf("administrator %s not found", admin.Name.Content)
+	}
+
+	diffs := []*Diff{}
+	if rot.Email != nil && !strings.EqualFold(deref(ctfdU.Email), rot.Email.Content) {
+		diffs = append(diffs, &Diff{
+			Kind:    DiffChanged,
+			Key:     "admin.email",
+			Current: deref(ctfdU.Email),
+			Desired: rot.Email.Content,
+		})
+	}
+	if rot.Password != nil {
+		if _, err := adminLogin(ctx, client.url, admin.Name.Content, rot.Password.Content, opts...); err != nil {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffChanged,
+				Key:     "admin.password",
+				Current: hideValue(admin.Password.Content),
+				Desired: hideValue(rot.Password.Content),
+			})
+		}
+	}
+	return diffs, nil
+}
+
+// adminRotated returns whether the CTFd administrator already has the rotated credentials.
+func adminRotated(ctx context.Context, url string, ctfdU *api.User, admin Admin, opts ...Option) bool {
+	rot := admin.Rotate
+	if rot.Email != nil && !strings.EqualFold(deref(ctfdU.Email), rot.Email.Content) {
+		return false
+	}
+	if rot.Password == nil {
+		return true
+	}
+	_, err := adminLogin(ctx, url, admin.Name.Content, rot.Password.Content, opts...)
+	return err == nil
+}
+
+// adminLogin returns a client logged in with the credentials, on a fresh session.
+// As CTFd responds to a failed login with the login page, it checks the session
+// is authenticated as the administrator.
+func adminLogin(ctx context.Context, url, name, password string, opts ...Option) (*Client, error) {
+	nonce, session, err := GetNonceAndSession(ctx, url, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting CTFd nonce and session")
+	}
+	client := NewClient(url, nonce, session, "")
+	if err := client.Login(ctx, &api.LoginParams{
+		Name:     name,
+		Password: password,
+	}, opts...); err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	me, err := client.GetUsersMe(ctx, opts...)
+	if err != nil || me.Name != name {
+		return nil, fmt.Errorf("invalid credentials for administrator %s", name)
+	}
+	return client, nil
+}
+
+// findAdmin returns the CTFd administrator with the given name, or nil if it does not exist.
+func findAdmin(ctx context.Context, client *Client, name string, opts ...Option) (*api.User, error) {
+	users, err := client.SearchUsers(ctx, "name", name, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	for _, u := range users {
+		if u.Name == name && deref(u.Type) == "admin" {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+// rotatedPassword returns the administrator password once rotated.
+func rotatedPassword(admin Admin) string {
+	if admin.Rotate != nil && admin.Rotate.Password != nil {
+		return admin.Rotate.Password.Content
+	}
+	return admin.Password.Content
+}
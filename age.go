@@ -0,0 +1,142 @@
+package ctfdsetup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AgeIdentity is the age identity file the encrypted values of the configuration
+// are decrypted with.
+var AgeIdentity string
+
+var (
+	decryptedMx sync.Mutex
+	// decrypted are the values decrypted from the configuration, that must
+	// never be displayed.
+	decrypted []string
+)
+
+// isEncrypted returns whether the value is an age-encrypted armored string.
+func isEncrypted(v string) bool {
+	return strings.HasPrefix(strings.TrimSpace(v), armor.Header)
+}
+
+// decrypt returns the plaintext of an age-encrypted armored string, using
+// the AgeIdentity file. The plaintext is then kept secret from logs and plan.
+func decrypt(v string) ([]byte, error) {
+	if AgeIdentity == "" {
+		return nil, errors.New("encrypted value found but no age identity file defined")
+	}
+	f, err := os.Open(AgeIdentity)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening age identity file")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing age identity file")
+	}
+
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(strings.TrimSpace(v))), ids...)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting value")
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting value")
+	}
+
+	if len(b) != 0 {
+		decryptedMx.Lock()
+		decrypted = append(decrypted, string(b))
+		decryptedMx.Unlock()
+	}
+	return b, nil
+}
+
+// minRedactedLength is the length from which a decrypted value is masked wherever
+// it appears. Shorter ones (e.g. "25") would mask unrelated values (e.g. "125"),
+// thus are only masked where they appear as a whole word.
+const minRedactedLength = 8
+
+// isDecrypted returns whether the value contains a decrypted one.
+func isDecrypted(v string) bool {
+	if v == "" {
+		return false
+	}
+	decryptedMx.Lock()
+	defer decryptedMx.Unlock()
+
+	for _, d := range decrypted {
+		if len(d) >= minRedactedLength && strings.Contains(v, d) || containsWord(v, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWord returns whether w appears in s, not surrounded by letters nor digits.
+func containsWord(s, w string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], w)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(w)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(s) || !isWordRune(after)) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// hideDecrypted masks the diffs values that contain a decrypted one.
+func hideDecrypted(diffs []*Diff) {
+	for _, d := range diffs {
+		if isDecrypted(d.Current) {
+			d.Current = hideValue(d.Current)
+		}
+		if isDecrypted(d.Desired) {
+			d.Desired = hideValue(d.Desired)
+		}
+	}
+}
+
+// redact masks the log message and fields that contain a decrypted value.
+// Fields are checked as they are encoded, such that e.g. arrays, errors and
+// reflected values are covered.
+func redact(msg string, fields []zap.Field) (string, []zap.Field) {
+	if isDecrypted(msg) {
+		msg = hideValue(msg)
+	}
+	for i, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		for _, v := range enc.Fields {
+			if s := fmt.Sprint(v); isDecrypted(s) {
+				fields[i] = zap.String(f.Key, hideValue(s))
+				break
+			}
+		}
+	}
+	return msg, fields
+}
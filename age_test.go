@@ -0,0 +1,111 @@
+package ctfdsetup_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_U_AgeDecrypt(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	idFile := filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(idFile, []byte(id.String()+"\n"), 0600))
+
+	ctfdsetup.AgeIdentity = idFile
+	t.Cleanup(func() {
+		ctfdsetup.AgeIdentity = ""
+	})
+
+	enc := encrypt(t, id.Recipient(), "mail-s3cr3t")
+
+	// FromEnv
+	fe := ctfdsetup.FromEnv{}
+	require.NoError(t, yaml.Unmarshal([]byte("|\n"+indent(enc)), &fe))
+	assert.Equal(t, "mail-s3cr3t", fe.Content)
+
+	// The ciphertext is written back, never the plaintext
+	b, err := yaml.Marshal(fe)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "mail-s3cr3t")
+	assert.Contains(t, string(b), armor.Header)
+
+	// File
+	file := ctfdsetup.File{}
+	require.NoError(t, yaml.Unmarshal([]byte("|\n"+indent(enc)), &file))
+	assert.Equal(t, []byte("mail-s3cr3t"), file.Content)
+
+	// Decrypted values are masked in the plan
+	diffs := []*ctfdsetup.Diff{
+		{Kind: ctfdsetup.DiffChanged, Key: "theme_header", Current: "old", Desired: "<p>mail-s3cr3t</p>"},
+		{Kind: ctfdsetup.DiffChanged, Key: "ctf_name", Current: "old", Desired: "new"},
+	}
+	ctfdsetup.HideDecrypted(diffs)
+	assert.Equal(t, "(sensitive)", diffs[0].Desired)
+	assert.Equal(t, "old", diffs[0].Current)
+	assert.Equal(t, "new", diffs[1].Desired)
+
+	// Short values are only masked as whole words
+	fe = ctfdsetup.FromEnv{}
+	require.NoError(t, yaml.Unmarshal([]byte("|\n"+indent(encrypt(t, id.Recipient(), "25"))), &fe))
+	diffs = []*ctfdsetup.Diff{
+		{Kind: ctfdsetup.DiffChanged, Key: "team_size", Current: "125", Desired: "25"},
+	}
+	ctfdsetup.HideDecrypted(diffs)
+	assert.Equal(t, "125", diffs[0].Current)
+	assert.Equal(t, "(sensitive)", diffs[0].Desired)
+
+	// Log messages and fields are masked, whatever their type
+	msg, fields := ctfdsetup.Redact("invalid team size 25", []zap.Field{
+		zap.String("size", "125"),
+		zap.Error(errors.New("got 25 members")),
+		zap.Strings("sizes", []string{"4", "25"}),
+		zap.Any("conf", map[string]any{"team_size": 25}),
+		zap.Stringer("stringer", stringer("25")),
+	})
+	assert.Equal(t, "(sensitive)", msg)
+	assert.Equal(t, zap.String("size", "125"), fields[0])
+	for _, f := range fields[1:] {
+		assert.Equal(t, zap.String(f.Key, "(sensitive)"), f)
+	}
+
+	// Another identity can't decrypt
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	fe = ctfdsetup.FromEnv{}
+	assert.Error(t, yaml.Unmarshal([]byte("|\n"+indent(encrypt(t, other.Recipient(), "x"))), &fe))
+}
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+// encrypt returns the plaintext encrypted to the recipient, as an armored string.
+func encrypt(t *testing.T, rcp age.Recipient, plaintext string) string {
+	buf := &bytes.Buffer{}
+	aw := armor.NewWriter(buf)
+	w, err := age.Encrypt(aw, rcp)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, plaintext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, aw.Close())
+	return buf.String()
+}
+
+// indent returns the lines indented such that they form a YAML block scalar.
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n  ") + "\n"
+}
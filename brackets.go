@@ -0,0 +1,240 @@
+package ctfdsetup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// bracketsState is the state key of the brackets IDs managed by ctfd-setup.
+const bracketsState = "brackets"
+
+// validateBrackets checks the brackets names are unique and their type matches the CTFd mode.
+func validateBrackets(brackets *Brackets, mode string) error {
+	if brackets == nil {
+		return nil
+	}
+	var merr error
+	names := map[string]struct{}{}
+	for _, bk := range brackets.List {
+		if _, ok := names[bk.Name]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("bracket %s: duplicated name", bk.Name))
+		}
+		names[bk.Name] = struct{}{}
+
+		if bk.Type != "" && bk.Type != mode {
+			merr = multierr.Append(merr, fmt.Errorf("bracket %s: type %s does not match the mode %s", bk.Name, bk.Type, mode))
+		}
+	}
+	return merr
+}
+
+// updateBrackets creates and updates the configured brackets by name, then deletes
+// the managed ones that were removed if asked to.
+// Brackets that ctfd-setup did not create are never modified nor deleted, but
+// reported as conflicting.
+func updateBrackets(ctx context.Context, client *Client, brackets *Brackets, mode string, current map[string]string, opts ...Option) error {
+	// Nothing to manage
+	if len(brackets.List) == 0 && !brackets.Prune {
+		return nil
+	}
+
+	managed := []int{}
+	if err := readState(current, bracketsState, &managed); err != nil {
+		return err
+	}
+
+	ctfdBks, err := client.GetBrackets(ctx, nil, opts...)
+	if err != nil {
+		return &ErrClient{err: err}
+	}
+
+	// Forget the managed brackets that were deleted by other means
+	manifest := slices.DeleteFunc(slices.Clone(managed), func(id int) bool {
+		return !slices.ContainsFunc(ctfdBks, func(bk *api.Bracket) bool {
+			return bk.ID == id
+		})
+	})
+
+	var merr error
+	for _, bk := range brackets.List {
+		typ := bracketType(bk, mode)
+
+		idx := slices.IndexFunc(ctfdBks, func(ctfdBk *api.Bracket) bool {
+			return ctfdBk.Name == bk.Name
+		})
+		if idx == -1 {
+			// CREATE
+			Log().Info(ctx, "creating bracket",
+				zap.String("name", bk.Name),
+			)
+			ctfdBk, err := client.PostBrackets(ctx, &api.PostBracketsParams{
+				Name:        bk.Name,
+				Description: bk.Description,
+				Type:        typ,
+			}, opts...)
+			if err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "creating bracket %s", bk.Name))
+				continue
+			}
+			manifest = append(manifest, ctfdBk.ID)
+			continue
+		}
+
+		// CONFLICT
+		ctfdBk := ctfdBks[idx]
+		if !slices.Contains(manifest, ctfdBk.ID) {
+			Log().Error(ctx, "bracket is not managed by ctfd-setup, skipping it",
+				zap.String("name", bk.Name),
+				zap.Int("id", ctfdBk.ID),
+			)
+			merr = multierr.Append(merr, fmt.Errorf("bracket %s conflicts with an unmanaged bracket (id %d)", bk.Name, ctfdBk.ID))
+			continue
+		}
+
+		// UPDATE
+		if ctfdBk.Description != bk.Description || ctfdBk.Type != typ {
+			Log().Info(ctx, "updating bracket",
+				zap.String("name", bk.Name),
+				zap.Int("id", ctfdBk.ID),
+			)
+			if _, err := client.PatchBrackets(ctx, ctfdBk.ID, &api.PatchBracketsParams{
+				Name:        &bk.Name,
+				Description: &bk.Description,
+				Type:        &typ,
+			}, opts...); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "updating bracket %s", bk.Name))
+				continue
+			}
+		}
+	}
+
+	// DELETE
+	if brackets.Prune {
+		for _, ctfdBk := range prunedBrackets(manifest, ctfdBks, brackets.List) {
+			Log().Info(ctx, "deleting bracket",
+				zap.String("name", ctfdBk.Name),
+				zap.Int("id", ctfdBk.ID),
+			)
+			if err := client.DeleteBrackets(ctx, ctfdBk.ID, opts...); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "deleting bracket %s", ctfdBk.Name))
+				continue
+			}
+			manifest = slices.DeleteFunc(manifest, func(id int) bool {
+				return id == ctfdBk.ID
+			})
+		}
+	}
+
+	// Save the manifest if it changed
+	slices.Sort(manifest)
+	slices.Sort(managed)
+	if !slices.Equal(manifest, managed) {
+		if err := writeState(ctx, client, bracketsState, manifest, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrap(err, "saving brackets manifest"))
+		}
+	}
+	return merr
+}
+
+// diffBrackets compares the CTFd brackets to the configured ones, per field.
+func diffBrackets(ctx context.Context, client *Client, bare bool, brackets *Brackets, mode string, current map[string]string, opts ...Option) ([]*Diff, error) {
+	// Nothing to manage
+	if len(brackets.List) == 0 && !brackets.Prune {
+		return nil, nil
+	}
+
+	ctfdBks := []*api.Bracket{}
+	managed := []int{}
+	if !bare {
+		var err error
+		ctfdBks, err = client.GetBrackets(ctx, nil, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		if err := readState(current, bracketsState, &managed); err != nil {
+			return nil, err
+		}
+	}
+
+	diffs := []*Diff{}
+	for _, bk := range brackets.List {
+		key := "brackets." + bk.Name
+
+		idx := slices.IndexFunc(ctfdBks, func(ctfdBk *api.Bracket) bool {
+			return ctfdBk.Name == bk.Name
+		})
+		if idx == -1 {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffAdded,
+				Key:     key,
+				Desired: bk.Name,
+			})
+			continue
+		}
+		if !slices.Contains(managed, ctfdBks[idx].ID) {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffChanged,
+				Key:     key,
+				Current: "(unmanaged) " + ctfdBks[idx].Name,
+				Desired: bk.Name,
+			})
+			continue
+		}
+
+		for _, f := range []struct {
+			name             string
+			current, desired string
+		}{
+			{"description", ctfdBks[idx].Description, bk.Description},
+			{"type", ctfdBks[idx].Type, bracketType(bk, mode)},
+		} {
+			if f.current == f.desired {
+				continue
+			}
+			diffs = append(diffs, &Diff{
+				Kind:    DiffChanged,
+				Key:     key + "." + f.name,
+				Current: f.current,
+				Desired: f.desired,
+			})
+		}
+	}
+
+	if brackets.Prune {
+		for _, ctfdBk := range prunedBrackets(managed, ctfdBks, brackets.List) {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffRemoved,
+				Key:     "brackets." + ctfdBk.Name,
+				Current: ctfdBk.Name,
+			})
+		}
+	}
+	return diffs, nil
+}
+
+// prunedBrackets returns the managed CTFd brackets that are not configured anymore.
+func prunedBrackets(managed []int, ctfdBks []*api.Bracket, list []*Bracket) []*api.Bracket {
+	pruned := []*api.Bracket{}
+	for _, ctfdBk := range ctfdBks {
+		if !slices.Contains(managed, ctfdBk.ID) {
+			continue
+		}
+		if !slices.ContainsFunc(list, func(bk *Bracket) bool {
+			return bk.Name == ctfdBk.Name
+		}) {
+			pruned = append(pruned, ctfdBk)
+		}
+	}
+	return pruned
+}
+
+// bracketType returns the type of the bracket, defaulting to the CTFd mode.
+func bracketType(bk *Bracket, mode string) string {
+	return orDefault(bk.Type, mode)
+}
@@ -0,0 +1,788 @@
+package ctfdsetup
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	// challengesState is the state key of the challenges managed by ctfd-setup,
+	// as a map of their key to their CTFd ID.
+	challengesState = "challenges"
+
+	// flagCaseInsensitive is the data CTFd expects for case insensitive static flags.
+	flagCaseInsensitive = "case_insensitive"
+)
+
+// validateChallenges checks the challenges constraints the schema cannot express.
+func validateChallenges(challs *Challenges) error {
+	if challs == nil {
+		return nil
+	}
+	list, err := challs.all()
+	if err != nil {
+		return err
+	}
+
+	var merr error
+	keys := map[string]struct{}{}
+	for _, ch := range list {
+		key := ch.key()
+		if _, ok := keys[key]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("challenge %s: duplicated key", key))
+		}
+		keys[key] = struct{}{}
+
+		if challengeType(ch) == "dynamic" && ch.Dynamic == nil {
+			merr = multierr.Append(merr, fmt.Errorf("challenge %s: dynamic challenges require the dynamic scoring parameters", key))
+		}
+		if challengeType(ch) != "dynamic" && ch.Dynamic != nil {
+			merr = multierr.Append(merr, fmt.Errorf("challenge %s: dynamic scoring parameters require the dynamic type", key))
+		}
+		for _, f := range ch.Files {
+			if f.Name == "" {
+				merr = multierr.Append(merr, fmt.Errorf("challenge %s: files must be defined with from_file", key))
+			}
+		}
+		if ch.Requirements != nil && slices.Contains(ch.Requirements.Prerequisites, key) {
+			merr = multierr.Append(merr, fmt.Errorf("challenge %s: can't require itself", key))
+		}
+	}
+	return merr
+}
+
+// all returns the challenges of the list, followed by the ones imported from ctfcli.
+func (challs *Challenges) all() ([]*Challenge, error) {
+	list := make([]*Challenge, 0, len(challs.List))
+	list = append(list, challs.List...)
+	for _, dir := range challs.FromCtfcli {
+		imported, err := challengesFromCtfcli(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "importing challenges from %s", dir)
+		}
+		list = append(list, imported...)
+	}
+	return list, nil
+}
+
+// key returns the stable key identifying the challenge, defaulting to its name.
+func (ch *Challenge) key() string {
+	if ch.Key != "" {
+		return ch.Key
+	}
+	return ch.Name
+}
+
+// updateChallenges creates and updates the configured challenges, then deletes the
+// managed ones that were removed if asked to.
+// Challenges are matched by their key through the state, such that a renamed
+// challenge is updated in place. Challenges created by other means are never
+// touched: a configured challenge with the same name is reported as conflicting.
+func updateChallenges(ctx context.Context, client *Client, challs *Challenges, current map[string]string, opts ...Option) error {
+	list, err := challs.all()
+	if err != nil {
+		return err
+	}
+
+	// Nothing to manage
+	if len(list) == 0 && !challs.Prune {
+		return nil
+	}
+
+	managed := map[string]int{}
+	if err := readState(current, challengesState, &managed); err != nil {
+		return err
+	}
+
+	ctfdChalls, err := client.GetChallenges(ctx, &api.GetChallengesParams{
+		View: ptr("admin"),
+	}, opts...)
+	if err != nil {
+		return &ErrClient{err: err}
+	}
+
+	// Forget the managed challenges that were deleted by other means
+	manifest := maps.Clone(managed)
+	maps.DeleteFunc(manifest, func(_ string, id int) bool {
+		return !slices.ContainsFunc(ctfdChalls, func(c *api.Challenge) bool {
+			return c.ID == id
+		})
+	})
+
+	var merr error
+	files := &challengeFiles{}
+	for _, ch := range list {
+		key := ch.key()
+		id, ok := manifest[key]
+		if !ok {
+			// CONFLICT
+			if c := unmanagedChallenge(ctfdChalls, manifest, ch.Name); c != nil {
+				Log().Error(ctx, "challenge is not managed by ctfd-setup, skipping it",
+					zap.String("key", key),
+					zap.Int("id", c.ID),
+				)
+				merr = multierr.Append(merr, fmt.Errorf("challenge %s conflicts with an unmanaged challenge (id %d)", key, c.ID))
+				continue
+			}
+
+			// CREATE
+			Log().Info(ctx, "creating challenge",
+				zap.String("key", key),
+			)
+			c, err := client.PostChallenges(ctx, postChallengeParams(ch), opts...)
+			if err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "creating challenge %s", key))
+				continue
+			}
+			id = c.ID
+			manifest[key] = id
+		} else {
+			// UPDATE
+			ctfdC, err := client.GetChallenge(ctx, id, opts...)
+			if err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "getting challenge %s", key))
+				continue
+			}
+			if ctfdC.Type != challengeType(ch) {
+				merr = multierr.Append(merr, fmt.Errorf("challenge %s: type can't be changed from %s to %s, delete it first", key, ctfdC.Type, challengeType(ch)))
+				continue
+			}
+			if len(diffChallenge(key, ctfdC, ch)) != 0 {
+				Log().Info(ctx, "updating challenge",
+					zap.String("key", key),
+					zap.Int("id", id),
+				)
+				if _, err := client.PatchChallenge(ctx, id, patchChallengeParams(ch), opts...); err != nil {
+					merr = multierr.Append(merr, errors.Wrapf(err, "updating challenge %s", key))
+					continue
+				}
+			}
+		}
+
+		if err := updateChallengeResources(ctx, client, id, ch, files, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "challenge %s", key))
+		}
+	}
+
+	// Requirements, once all challenges exist such that they can refer to each other
+	refs, ids := challengeRefs(ctfdChalls, manifest)
+	for _, ch := range list {
+		id, ok := manifest[ch.key()]
+		if !ok {
+			continue
+		}
+		if err := updateChallengeRequirements(ctx, client, id, ch, refs, ids, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "challenge %s", ch.key()))
+		}
+	}
+
+	// DELETE
+	if challs.Prune {
+		for _, key := range prunedChallenges(manifest, list) {
+			Log().Info(ctx, "deleting challenge",
+				zap.String("key", key),
+				zap.Int("id", manifest[key]),
+			)
+			if err := client.DeleteChallenge(ctx, manifest[key], opts...); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "deleting challenge %s", key))
+				continue
+			}
+			delete(manifest, key)
+		}
+	}
+
+	// Save the manifest if it changed, even on partial failure to keep track
+	// of what got created
+	if !maps.Equal(manifest, managed) {
+		if err := writeState(ctx, client, challengesState, manifest, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrap(err, "saving challenges manifest"))
+		}
+	}
+	return merr
+}
+
+// updateChallengeResources reconciles the flags, hints, tags, topics and files of a challenge.
+func updateChallengeResources(ctx context.Context, client *Client, id int, ch *Challenge, files *challengeFiles, opts ...Option) error {
+	res, err := getChallengeResources(ctx, client, id, files, opts...)
+	if err != nil {
+		return err
+	}
+	chg := compareChallengeResources(res, ch)
+
+	var merr error
+	for _, f := range chg.delFlags {
+		merr = multierr.Append(merr, client.DeleteFlag(ctx, f.ID, opts...))
+	}
+	for _, f := range chg.addFlags {
+		_, err := client.PostFlags(ctx, &api.PostFlagsParams{
+			Challenge: id,
+			Content:   f.Content.Content,
+			Data:      flagData(f),
+			Type:      flagType(f),
+		}, opts...)
+		merr = multierr.Append(merr, err)
+	}
+	for _, h := range chg.delHints {
+		merr = multierr.Append(merr, client.DeleteHint(ctx, h.ID, opts...))
+	}
+	for _, h := range chg.addHints {
+		_, err := client.PostHints(ctx, &api.PostHintsParams{
+			ChallengeID: id,
+			Title:       h.Title,
+			Content:     h.Content,
+			Cost:        h.Cost,
+		}, opts...)
+		merr = multierr.Append(merr, err)
+	}
+	for _, t := range chg.delTags {
+		merr = multierr.Append(merr, client.DeleteTag(ctx, t.ID, opts...))
+	}
+	for _, t := range chg.addTags {
+		_, err := client.PostTags(ctx, &api.PostTagsParams{
+			Challenge: id,
+			Value:     t,
+		}, opts...)
+		merr = multierr.Append(merr, err)
+	}
+	for _, t := range chg.delTopics {
+		merr = multierr.Append(merr, client.DeleteTopic(ctx, &api.DeleteTopicArgs{
+			ID:   strconv.Itoa(t.ID),
+			Type: "challenge",
+		}, opts...))
+	}
+	for _, t := range chg.addTopics {
+		_, err := client.PostTopics(ctx, &api.PostTopicsParams{
+			Challenge: id,
+			Type:      "challenge",
+			Value:     t,
+		}, opts...)
+		merr = multierr.Append(merr, err)
+	}
+	for _, f := range chg.delFiles {
+		merr = multierr.Append(merr, client.DeleteFile(ctx, f.ID, opts...))
+	}
+	for _, f := range chg.addFiles {
+		_, err := client.PostFiles(ctx, &api.PostFilesParams{
+			Files: []*api.InputFile{
+				{
+					Name:    filepath.Base(f.Name),
+					Content: f.Content,
+				},
+			},
+			Challenge: &id,
+		}, opts...)
+		merr = multierr.Append(merr, err)
+	}
+	return merr
+}
+
+// challengeResources are the sub-resources of a CTFd challenge.
+type challengeResources struct {
+	flags  []*api.Flag
+	hints  []*api.Hint
+	tags   []*api.Tag
+	topics []*api.Topic
+	files  []*api.File
+}
+
+// challengeFiles lists all the challenges files once for all the challenges, as
+// challenge files are listed without their SHA1 sum.
+type challengeFiles struct {
+	files []*api.File
+}
+
+func (cf *challengeFiles) get(ctx context.Context, client *Client, opts ...Option) ([]*api.File, error) {
+	if cf.files != nil {
+		return cf.files, nil
+	}
+	fs, err := client.GetFiles(ctx, &api.GetFilesParams{
+		Type: ptr("challenge"),
+	}, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	cf.files = append([]*api.File{}, fs...)
+	return cf.files, nil
+}
+
+func getChallengeResources(ctx context.Context, client *Client, id int, files *challengeFiles, opts ...Option) (*challengeResources, error) {
+	res := &challengeResources{}
+	var err error
+	if res.flags, err = client.GetChallengeFlags(ctx, id, opts...); err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	if res.hints, err = client.GetChallengeHints(ctx, id, opts...); err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	if res.tags, err = client.GetChallengeTags(ctx, id, opts...); err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	if res.topics, err = client.GetChallengeTopics(ctx, id, opts...); err != nil {
+		return nil, &ErrClient{err: err}
+	}
+
+	// Challenge files are listed without their SHA1 sum, thus get them from all
+	// the challenges files
+	cfs, err := client.GetChallengeFiles(ctx, id, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	if len(cfs) != 0 {
+		fs, err := files.get(ctx, client, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fs {
+			if slices.ContainsFunc(cfs, func(cf *api.File) bool {
+				return cf.ID == f.ID
+			}) {
+				res.files = append(res.files, f)
+			}
+		}
+	}
+	return res, nil
+}
+
+// challengeChanges are the sub-resources to create and delete for a challenge
+// to match its configuration.
+type challengeChanges struct {
+	addFlags  []*Flag
+	delFlags  []*api.Flag
+	addHints  []*Hint
+	delHints  []*api.Hint
+	addTags   []string
+	delTags   []*api.Tag
+	addTopics []string
+	delTopics []*api.Topic
+	addFiles  []*File
+	delFiles  []*api.File
+}
+
+// compareChallengeResources compares the sub-resources of a CTFd challenge to the
+// configured ones. Sub-resources have no stable identity, thus a modified one is
+// deleted then created again.
+func compareChallengeResources(res *challengeResources, ch *Challenge) *challengeChanges {
+	chg := &challengeChanges{}
+	chg.addFlags, chg.delFlags = compareSets(ch.Flags, res.flags, func(f *Flag, cf *api.Flag) bool {
+		return cf.Type == flagType(f) && cf.Content == f.Content.Content && cf.Data == flagData(f)
+	})
+	chg.addHints, chg.delHints = compareSets(ch.Hints, res.hints, func(h *Hint, chh *api.Hint) bool {
+		return deref(chh.Title) == deref(h.Title) && deref(chh.Content) == h.Content && chh.Cost == h.Cost
+	})
+	chg.addTags, chg.delTags = compareSets(ch.Tags, res.tags, func(t string, ct *api.Tag) bool {
+		return ct.Value == t
+	})
+	chg.addTopics, chg.delTopics = compareSets(ch.Topics, res.topics, func(t string, ct *api.Topic) bool {
+		return ct.Value == t
+	})
+	chg.addFiles, chg.delFiles = compareSets(ch.Files, res.files, func(f *File, cf *api.File) bool {
+		return path.Base(cf.Location) == filepath.Base(f.Name) && cf.SHA1sum == sha1sum(f.Content)
+	})
+	return chg
+}
+
+// compareSets returns the desired elements that have no current match, and the
+// current elements that match no desired one.
+func compareSets[D, C any](desired []D, current []C, same func(D, C) bool) (added []D, removed []C) {
+	for _, d := range desired {
+		if !slices.ContainsFunc(current, func(c C) bool { return same(d, c) }) {
+			added = append(added, d)
+		}
+	}
+	for _, c := range current {
+		if !slices.ContainsFunc(desired, func(d D) bool { return same(d, c) }) {
+			removed = append(removed, c)
+		}
+	}
+	return
+}
+
+// diffChallenge compares the attributes of a CTFd challenge to the configured ones.
+func diffChallenge(key string, ctfdC *api.Challenge, ch *Challenge) []*Diff {
+	attrs := []struct {
+		name             string
+		current, desired string
+	}{
+		{"name", ctfdC.Name, ch.Name},
+		{"category", ctfdC.Category, ch.Category},
+		{"description", "sha1:" + sha1sum([]byte(ctfdC.Description)), "sha1:" + sha1sum(challengeDescription(ch))},
+		{"connection_info", deref(ctfdC.ConnectionInfo), deref(ch.ConnectionInfo)},
+		{"max_attempts", strconv.Itoa(deref(ctfdC.MaxAttempts)), strconv.Itoa(deref(ch.MaxAttempts))},
+		{"state", ctfdC.State, challengeState(ch)},
+		{"type", ctfdC.Type, challengeType(ch)},
+	}
+	if challengeType(ch) == "dynamic" {
+		attrs = append(attrs, []struct {
+			name             string
+			current, desired string
+		}{
+			{"value", strconv.Itoa(deref(ctfdC.Initial)), strconv.Itoa(ch.Value)},
+			{"dynamic.minimum", strconv.Itoa(deref(ctfdC.Minimum)), strconv.Itoa(ch.Dynamic.Minimum)},
+			{"dynamic.decay", strconv.Itoa(deref(ctfdC.Decay)), strconv.Itoa(ch.Dynamic.Decay)},
+			{"dynamic.function", deref(ctfdC.Function), dynamicFunction(ch.Dynamic)},
+		}...)
+	} else {
+		attrs = append(attrs, struct {
+			name             string
+			current, desired string
+		}{"value", strconv.Itoa(ctfdC.Value), strconv.Itoa(ch.Value)})
+	}
+
+	diffs := []*Diff{}
+	for _, attr := range attrs {
+		if attr.current == attr.desired {
+			continue
+		}
+		diffs = append(diffs, &Diff{
+			Kind:    DiffChanged,
+			Key:     "challenges." + key + "." + attr.name,
+			Current: attr.current,
+			Desired: attr.desired,
+		})
+	}
+	return diffs
+}
+
+// diffChallenges compares the CTFd challenges to the configured ones, per attribute
+// and sub-resource. Challenges that ctfd-setup does not own are reported as
+// conflicting, as Setup would not overwrite them.
+func diffChallenges(ctx context.Context, client *Client, bare bool, challs *Challenges, current map[string]string, opts ...Option) ([]*Diff, error) {
+	list, err := challs.all()
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing to manage
+	if len(list) == 0 && !challs.Prune {
+		return nil, nil
+	}
+
+	ctfdChalls := []*api.Challenge{}
+	managed := map[string]int{}
+	if !bare {
+		var err error
+		ctfdChalls, err = client.GetChallenges(ctx, &api.GetChallengesParams{
+			View: ptr("admin"),
+		}, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		if err := readState(current, challengesState, &managed); err != nil {
+			return nil, err
+		}
+		maps.DeleteFunc(managed, func(_ string, id int) bool {
+			return !slices.ContainsFunc(ctfdChalls, func(c *api.Challenge) bool {
+				return c.ID == id
+			})
+		})
+	}
+
+	refs, _ := challengeRefs(ctfdChalls, managed)
+	files := &challengeFiles{}
+	diffs := []*Diff{}
+	for _, ch := range list {
+		key := ch.key()
+		id, ok := managed[key]
+		if !ok {
+			if c := unmanagedChallenge(ctfdChalls, managed, ch.Name); c != nil {
+				diffs = append(diffs, &Diff{
+					Kind:    DiffChanged,
+					Key:     "challenges." + key,
+					Current: "(unmanaged) " + c.Name,
+					Desired: ch.Name,
+				})
+				continue
+			}
+			diffs = append(diffs, &Diff{
+				Kind:    DiffAdded,
+				Key:     "challenges." + key,
+				Desired: ch.Name,
+			})
+			continue
+		}
+
+		ctfdC, err := client.GetChallenge(ctx, id, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		diffs = append(diffs, diffChallenge(key, ctfdC, ch)...)
+
+		res, err := getChallengeResources(ctx, client, id, files, opts...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diffChallengeResources(key, compareChallengeResources(res, ch))...)
+
+		reqs, err := client.GetChallengeRequirements(ctx, id, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		diffs = append(diffs, diffRequirements(key, reqs, ch.Requirements, refs)...)
+	}
+
+	// Challenges that are not configured are deleted if asked to
+	if challs.Prune {
+		for _, key := range prunedChallenges(managed, list) {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffRemoved,
+				Key:     "challenges." + key,
+				Current: key,
+			})
+		}
+	}
+	return diffs, nil
+}
+
+// diffChallengeResources turns the sub-resources changes of a challenge into diffs.
+// Flags are sensitive thus never displayed.
+func diffChallengeResources(key string, chg *challengeChanges) []*Diff {
+	key = "challenges." + key
+	diffs := []*Diff{}
+	added := func(name, desired string) {
+		diffs = append(diffs, &Diff{Kind: DiffAdded, Key: key + "." + name, Desired: desired})
+	}
+	removed := func(name, current string) {
+		diffs = append(diffs, &Diff{Kind: DiffRemoved, Key: key + "." + name, Current: current})
+	}
+
+	for range chg.addFlags {
+		added("flags", hideValue("flag"))
+	}
+	for range chg.delFlags {
+		removed("flags", hideValue("flag"))
+	}
+	for _, h := range chg.addHints {
+		added("hints", deref(h.Title))
+	}
+	for _, h := range chg.delHints {
+		removed("hints", deref(h.Title))
+	}
+	for _, t := range chg.addTags {
+		added("tags", t)
+	}
+	for _, t := range chg.delTags {
+		removed("tags", t.Value)
+	}
+	for _, t := range chg.addTopics {
+		added("topics", t)
+	}
+	for _, t := range chg.delTopics {
+		removed("topics", t.Value)
+	}
+	for _, f := range chg.addFiles {
+		added("files", filepath.Base(f.Name))
+	}
+	for _, f := range chg.delFiles {
+		removed("files", path.Base(f.Location))
+	}
+	return diffs
+}
+
+// updateChallengeRequirements patches the prerequisites of a challenge if they changed.
+func updateChallengeRequirements(ctx context.Context, client *Client, id int, ch *Challenge, refs map[int]string, ids map[string]int, opts ...Option) error {
+	current, err := client.GetChallengeRequirements(ctx, id, opts...)
+	if err != nil {
+		return &ErrClient{err: err}
+	}
+	if len(diffRequirements(ch.key(), current, ch.Requirements, refs)) == 0 {
+		return nil
+	}
+
+	reqs := &api.Requirements{
+		Prerequisites: []int{},
+	}
+	if ch.Requirements != nil {
+		for _, p := range ch.Requirements.Prerequisites {
+			pid, ok := ids[p]
+			if !ok {
+				return fmt.Errorf("prerequisite %s does not exist", p)
+			}
+			reqs.Prerequisites = append(reqs.Prerequisites, pid)
+		}
+		reqs.Anonymize = &ch.Requirements.Anonymize
+	}
+
+	Log().Info(ctx, "updating challenge requirements",
+		zap.String("key", ch.key()),
+		zap.Int("id", id),
+	)
+	params := patchChallengeParams(ch)
+	params.Requirements = reqs
+	if _, err := client.PatchChallenge(ctx, id, params, opts...); err != nil {
+		return errors.Wrap(err, "updating requirements")
+	}
+	return nil
+}
+
+// diffRequirements compares the prerequisites of a CTFd challenge to the configured ones.
+func diffRequirements(key string, current *api.Requirements, reqs *Requirements, refs map[int]string) []*Diff {
+	cur := []string{}
+	curAnonymize := false
+	if current != nil {
+		for _, id := range current.Prerequisites {
+			ref, ok := refs[id]
+			if !ok {
+				ref = "#" + strconv.Itoa(id)
+			}
+			cur = append(cur, ref)
+		}
+		curAnonymize = deref(current.Anonymize)
+	}
+	des := []string{}
+	desAnonymize := false
+	if reqs != nil {
+		des = append(des, reqs.Prerequisites...)
+		desAnonymize = reqs.Anonymize
+	}
+	slices.Sort(cur)
+	slices.Sort(des)
+
+	key = "challenges." + key + ".requirements"
+	diffs := []*Diff{}
+	if !slices.Equal(cur, des) {
+		diffs = append(diffs, &Diff{
+			Kind:    DiffChanged,
+			Key:     key,
+			Current: strings.Join(cur, ", "),
+			Desired: strings.Join(des, ", "),
+		})
+	}
+	if len(des) != 0 && curAnonymize != desAnonymize {
+		diffs = append(diffs, &Diff{
+			Kind:    DiffChanged,
+			Key:     key + ".anonymize",
+			Current: strconv.FormatBool(curAnonymize),
+			Desired: strconv.FormatBool(desAnonymize),
+		})
+	}
+	return diffs
+}
+
+// challengeRefs returns how requirements refer to the CTFd challenges: by key if
+// managed, else by name. It maps the IDs to their reference, and the other way around.
+func challengeRefs(ctfdChalls []*api.Challenge, managed map[string]int) (map[int]string, map[string]int) {
+	refs := map[int]string{}
+	ids := map[string]int{}
+	for _, c := range ctfdChalls {
+		refs[c.ID] = c.Name
+		ids[c.Name] = c.ID
+	}
+	// Managed challenges keys take precedence over names
+	for key, id := range managed {
+		if name, ok := refs[id]; ok && ids[name] == id {
+			delete(ids, name)
+		}
+		refs[id] = key
+		ids[key] = id
+	}
+	return refs, ids
+}
+
+// unmanagedChallenge returns the CTFd challenge with the given name that is
+// not managed by ctfd-setup, if any.
+func unmanagedChallenge(ctfdChalls []*api.Challenge, managed map[string]int, name string) *api.Challenge {
+	ids := slices.Collect(maps.Values(managed))
+	for _, c := range ctfdChalls {
+		if c.Name == name && !slices.Contains(ids, c.ID) {
+			return c
+		}
+	}
+	return nil
+}
+
+// prunedChallenges returns the keys of the managed challenges that are not configured anymore.
+func prunedChallenges(managed map[string]int, list []*Challenge) []string {
+	pruned := []string{}
+	for key := range managed {
+		if !slices.ContainsFunc(list, func(ch *Challenge) bool {
+			return ch.key() == key
+		}) {
+			pruned = append(pruned, key)
+		}
+	}
+	slices.Sort(pruned)
+	return pruned
+}
+
+func postChallengeParams(ch *Challenge) *api.PostChallengesParams {
+	params := &api.PostChallengesParams{
+		Name:           ch.Name,
+		Category:       ch.Category,
+		Description:    string(challengeDescription(ch)),
+		ConnectionInfo: ch.ConnectionInfo,
+		Value:          ch.Value,
+		Logic:          "any",
+		MaxAttempts:    ch.MaxAttempts,
+		State:          challengeState(ch),
+		Type:           challengeType(ch),
+	}
+	if challengeType(ch) == "dynamic" {
+		params.Initial = &ch.Value
+		params.Decay = &ch.Dynamic.Decay
+		params.Minimum = &ch.Dynamic.Minimum
+		params.Function = ptr(dynamicFunction(ch.Dynamic))
+	}
+	return params
+}
+
+func patchChallengeParams(ch *Challenge) *api.PatchChallengeParams {
+	params := &api.PatchChallengeParams{
+		Name:           ch.Name,
+		Category:       ch.Category,
+		Description:    string(challengeDescription(ch)),
+		ConnectionInfo: ptr(deref(ch.ConnectionInfo)),
+		MaxAttempts:    ptr(deref(ch.MaxAttempts)), // 0 resets to unlimited
+		State:          challengeState(ch),
+	}
+	if challengeType(ch) == "dynamic" {
+		// The value is computed by CTFd out of the initial one
+		params.Initial = &ch.Value
+		params.Decay = &ch.Dynamic.Decay
+		params.Minimum = &ch.Dynamic.Minimum
+		params.Function = ptr(dynamicFunction(ch.Dynamic))
+	} else {
+		params.Value = &ch.Value
+	}
+	return params
+}
+
+func challengeDescription(ch *Challenge) []byte {
+	if ch.Description == nil {
+		return nil
+	}
+	return ch.Description.Content
+}
+
+func challengeType(ch *Challenge) string {
+	return orDefault(ch.Type, "standard")
+}
+
+func challengeState(ch *Challenge) string {
+	return orDefault(ch.State, "visible")
+}
+
+func dynamicFunction(dyn *DynamicScoring) string {
+	return orDefault(dyn.Function, "logarithmic")
+}
+
+func flagType(f *Flag) string {
+	return orDefault(f.Type, "static")
+}
+
+func flagData(f *Flag) string {
+	if f.CaseInsensitive {
+		return flagCaseInsensitive
+	}
+	return ""
+}
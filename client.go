@@ -1,11 +1,13 @@
 // Contains a wrapper around github.com/ctfer-io/go-ctfd.
 //
-// It injects spans for all API operations.
+// It injects spans for all API operations, and retries them on transient
+// errors if asked to.
 
 package ctfdsetup
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -13,7 +15,12 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-var apiTransport = api.WithTransport(otelhttp.NewTransport(http.DefaultTransport))
+var (
+	transport = &recordingTransport{
+		next: otelhttp.NewTransport(http.DefaultTransport),
+	}
+	apiTransport = api.WithTransport(transport)
+)
 
 func apiOptions(ctx context.Context) []api.Option {
 	return []api.Option{
@@ -28,7 +35,11 @@ func GetNonceAndSession(ctx context.Context, url string, opts ...Option) (nonce,
 
 	LogAPICall(ctx)
 
-	return api.GetNonceAndSession(url, apiOptions(ctx)...)
+	err = retry(ctx, opts, func(ctx context.Context) error {
+		nonce, session, err = api.GetNonceAndSession(url, apiOptions(ctx)...)
+		return err
+	})
+	return
 }
 
 type Client struct {
@@ -49,23 +60,29 @@ func (cli *Client) Bare(ctx context.Context, opts ...Option) (bool, error) {
 
 	LogAPICall(ctx)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cli.url+"/setup", nil)
-	if err != nil {
-		return false, err
-	}
-
-	client := http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Transport: otelhttp.NewTransport(http.DefaultTransport),
-	}
-	res, err := client.Do(req)
-	if err != nil {
-		return false, &ErrClient{err: err}
-	}
-
-	return res.StatusCode == 200, nil // 302 if already setup
+	return retryValue(ctx, opts, func(ctx context.Context) (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cli.url+"/setup", nil)
+		if err != nil {
+			return false, err
+		}
+
+		client := http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+			Transport: transport,
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return false, &ErrClient{err: err}
+		}
+		_ = res.Body.Close()
+		if res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests {
+			return false, &ErrClient{err: fmt.Errorf("unexpected status %d", res.StatusCode)}
+		}
+
+		return res.StatusCode == 200, nil // 302 if already setup
+	})
 }
 
 func (cli *Client) Login(ctx context.Context, params *api.LoginParams, opts ...Option) error {
@@ -74,7 +91,9 @@ func (cli *Client) Login(ctx context.Context, params *api.LoginParams, opts ...O
 
 	LogAPICall(ctx)
 
-	return cli.sub.Login(params, apiOptions(ctx)...)
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.Login(params, apiOptions(ctx)...)
+	})
 }
 
 func (cli *Client) Setup(ctx context.Context, params *api.SetupParams, opts ...Option) error {
@@ -83,7 +102,9 @@ func (cli *Client) Setup(ctx context.Context, params *api.SetupParams, opts ...O
 
 	LogAPICall(ctx)
 
-	return cli.sub.Setup(params, apiOptions(ctx)...)
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.Setup(params, apiOptions(ctx)...)
+	})
 }
 
 // region pages
@@ -94,7 +115,9 @@ func (cli *Client) GetPages(ctx context.Context, params *api.GetPagesParams, opt
 
 	LogAPICall(ctx)
 
-	return cli.sub.GetPages(params, apiOptions(ctx)...)
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Page, error) {
+		return cli.sub.GetPages(params, apiOptions(ctx)...)
+	})
 }
 
 func (cli *Client) PatchPage(ctx context.Context, id int, params *api.PatchPageParams, opts ...Option) (*api.Page, error) {
@@ -103,7 +126,9 @@ func (cli *Client) PatchPage(ctx context.Context, id int, params *api.PatchPageP
 
 	LogAPICall(ctx)
 
-	return cli.sub.PatchPage(strconv.Itoa(id), params, apiOptions(ctx)...)
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Page, error) {
+		return cli.sub.PatchPage(strconv.Itoa(id), params, apiOptions(ctx)...)
+	})
 }
 
 func (cli *Client) PostPages(ctx context.Context, params *api.PostPagesParams, opts ...Option) (*api.Page, error) {
@@ -112,7 +137,20 @@ func (cli *Client) PostPages(ctx context.Context, params *api.PostPagesParams, o
 
 	LogAPICall(ctx)
 
-	return cli.sub.PostPages(params, apiOptions(ctx)...)
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Page, error) {
+		return cli.sub.PostPages(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetPage(ctx context.Context, id int, opts ...Option) (*api.Page, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Page, error) {
+		return cli.sub.GetPage(strconv.Itoa(id), apiOptions(ctx)...)
+	})
 }
 
 func (cli *Client) DeletePage(ctx context.Context, id int, opts ...Option) error {
@@ -121,7 +159,9 @@ func (cli *Client) DeletePage(ctx context.Context, id int, opts ...Option) error
 
 	LogAPICall(ctx)
 
-	return cli.sub.DeletePage(strconv.Itoa(id), apiOptions(ctx)...)
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeletePage(strconv.Itoa(id), apiOptions(ctx)...)
+	})
 }
 
 // region files
@@ -132,7 +172,9 @@ func (cli *Client) GetFiles(ctx context.Context, params *api.GetFilesParams, opt
 
 	LogAPICall(ctx)
 
-	return cli.sub.GetFiles(params, apiOptions(ctx)...)
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.File, error) {
+		return cli.sub.GetFiles(params, apiOptions(ctx)...)
+	})
 }
 
 func (cli *Client) PostFiles(ctx context.Context, params *api.PostFilesParams, opts ...Option) ([]*api.File, error) {
@@ -141,7 +183,31 @@ func (cli *Client) PostFiles(ctx context.Context, params *api.PostFilesParams, o
 
 	LogAPICall(ctx)
 
-	return cli.sub.PostFiles(params, apiOptions(ctx)...)
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.File, error) {
+		return cli.sub.PostFiles(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetFileContent(ctx context.Context, file *api.File, opts ...Option) ([]byte, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]byte, error) {
+		return cli.sub.GetFileContent(file, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteFile(ctx context.Context, id int, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteFile(strconv.Itoa(id), apiOptions(ctx)...)
+	})
 }
 
 // region logos/icons
@@ -152,7 +218,9 @@ func (cli *Client) PatchConfigsCTFLogo(ctx context.Context, params *api.PatchCon
 
 	LogAPICall(ctx)
 
-	return cli.sub.PatchConfigsCTFLogo(params, apiOptions(ctx)...)
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.ThemeImage, error) {
+		return cli.sub.PatchConfigsCTFLogo(params, apiOptions(ctx)...)
+	})
 }
 
 func (cli *Client) PatchConfigsCTFSmallIcon(ctx context.Context, params *api.PatchConfigsCTFLogo, opts ...Option) (*api.ThemeImage, error) {
@@ -161,16 +229,567 @@ func (cli *Client) PatchConfigsCTFSmallIcon(ctx context.Context, params *api.Pat
 
 	LogAPICall(ctx)
 
-	return cli.sub.PatchConfigsCTFSmallIcon(params, apiOptions(ctx)...)
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.ThemeImage, error) {
+		return cli.sub.PatchConfigsCTFSmallIcon(params, apiOptions(ctx)...)
+	})
 }
 
 // region configs
 
+func (cli *Client) GetConfigs(ctx context.Context, params *api.GetConfigsParams, opts ...Option) ([]*api.Config, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Config, error) {
+		return cli.sub.GetConfigs(params, apiOptions(ctx)...)
+	})
+}
+
 func (cli *Client) PatchConfigs(ctx context.Context, params *api.PatchConfigsParams, opts ...Option) error {
 	ctx, span := StartAPISpan(ctx, getTracer(opts...))
 	defer span.End()
 
 	LogAPICall(ctx)
 
-	return cli.sub.PatchConfigs(params, apiOptions(ctx)...)
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.PatchConfigs(params, apiOptions(ctx)...)
+	})
+}
+
+// PatchConfigsValues patches only the given CTFd configs, indexed by their key.
+func (cli *Client) PatchConfigsValues(ctx context.Context, values map[string]any, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.Patch("/configs", values, nil, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetConfigsFields(ctx context.Context, opts ...Option) ([]*api.ConfigField, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.ConfigField, error) {
+		return cli.sub.GetConfigsFields(nil, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) PostConfigFields(ctx context.Context, params *api.PostConfigFieldsParams, opts ...Option) (*api.ConfigField, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.ConfigField, error) {
+		return cli.sub.PostConfigFields(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) PatchConfigsField(ctx context.Context, id int, params *api.PatchConfigsFieldParams, opts ...Option) (*api.ConfigField, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.ConfigField, error) {
+		return cli.sub.PatchConfigsField(strconv.Itoa(id), params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteConfigsField(ctx context.Context, id int, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteConfigsField(strconv.Itoa(id), apiOptions(ctx)...)
+	})
+}
+
+// accountFields are the custom fields values of a user or a team.
+// go-ctfd models are not used as they can't decode boolean values.
+type accountFields struct {
+	ID     int `json:"id"`
+	Fields []struct {
+		FieldID int `json:"field_id"`
+		Value   any `json:"value"`
+	} `json:"fields"`
+}
+
+// pageParams selects a page of the resources, including the hidden and banned ones.
+type pageParams struct {
+	Page    int    `schema:"page"`
+	PerPage int    `schema:"per_page"`
+	View    string `schema:"view"`
+}
+
+// GetAccountsFields returns a page of the custom fields values of the users or teams.
+// The page is empty once the last one is passed.
+func (cli *Client) GetAccountsFields(ctx context.Context, typ string, page int, opts ...Option) ([]*accountFields, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*accountFields, error) {
+		accounts := []*accountFields{}
+		err := cli.sub.Get("/"+typ+"s", &pageParams{Page: page, PerPage: 100, View: "admin"}, &accounts, apiOptions(ctx)...)
+		return accounts, err
+	})
+}
+
+// region users
+
+func (cli *Client) GetUsersMe(ctx context.Context, opts ...Option) (*api.User, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.User, error) {
+		return cli.sub.GetUsersMe(apiOptions(ctx)...)
+	})
+}
+
+// GetUsers returns a page of the users, including the hidden and banned ones.
+// The page is empty once the last one is passed.
+func (cli *Client) GetUsers(ctx context.Context, page int, opts ...Option) ([]*api.User, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.User, error) {
+		users := []*api.User{}
+		err := cli.sub.Get("/users", &pageParams{Page: page, PerPage: 100, View: "admin"}, &users, apiOptions(ctx)...)
+		return users, err
+	})
+}
+
+// searchParams searches resources by one of their fields, including the hidden
+// and banned ones. The go-ctfd params are not used as they filter on unset fields.
+type searchParams struct {
+	Q     string `schema:"q,omitempty"`
+	Field string `schema:"field,omitempty"`
+	View  string `schema:"view,omitempty"`
+}
+
+// SearchUsers returns the users whose field contains q.
+func (cli *Client) SearchUsers(ctx context.Context, field, q string, opts ...Option) ([]*api.User, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.User, error) {
+		users := []*api.User{}
+		err := cli.sub.Get("/users", &searchParams{Q: q, Field: field, View: "admin"}, &users, apiOptions(ctx)...)
+		return users, err
+	})
+}
+
+func (cli *Client) PostUsers(ctx context.Context, params *api.PostUsersParams, opts ...Option) (*api.User, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.User, error) {
+		return cli.sub.PostUsers(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) PatchUser(ctx context.Context, id int, params *api.PatchUsersParams, opts ...Option) (*api.User, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.User, error) {
+		return cli.sub.PatchUser(id, params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteUser(ctx context.Context, id int, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteUser(id, apiOptions(ctx)...)
+	})
+}
+
+// region teams
+
+// SearchTeams returns the teams whose field contains q.
+func (cli *Client) SearchTeams(ctx context.Context, field, q string, opts ...Option) ([]*api.Team, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Team, error) {
+		teams := []*api.Team{}
+		err := cli.sub.Get("/teams", &searchParams{Q: q, Field: field, View: "admin"}, &teams, apiOptions(ctx)...)
+		return teams, err
+	})
+}
+
+type postTeamsParams struct {
+	Name        string      `json:"name"`
+	Email       string      `json:"email,omitempty"`
+	Password    string      `json:"password"`
+	Affiliation *string     `json:"affiliation,omitempty"`
+	Country     *string     `json:"country,omitempty"`
+	Fields      []api.Field `json:"fields"`
+	BracketID   *string     `json:"bracket_id,omitempty"`
+}
+
+// PostTeams creates a team. The go-ctfd params are not used as they always send
+// the email, which CTFd rejects when empty.
+func (cli *Client) PostTeams(ctx context.Context, params *postTeamsParams, opts ...Option) (*api.Team, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Team, error) {
+		team := &api.Team{}
+		err := cli.sub.Post("/teams", params, &team, apiOptions(ctx)...)
+		return team, err
+	})
+}
+
+func (cli *Client) PatchTeam(ctx context.Context, id int, params *api.PatchTeamsParams, opts ...Option) (*api.Team, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Team, error) {
+		return cli.sub.PatchTeam(id, params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetTeamMembers(ctx context.Context, id int, opts ...Option) ([]int, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]int, error) {
+		return cli.sub.GetTeamMembers(id, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) PostTeamMembers(ctx context.Context, id int, params *api.PostTeamsMembersParams, opts ...Option) (int, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (int, error) {
+		return cli.sub.PostTeamMembers(id, params, apiOptions(ctx)...)
+	})
+}
+
+// region challenges
+
+func (cli *Client) GetChallenges(ctx context.Context, params *api.GetChallengesParams, opts ...Option) ([]*api.Challenge, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Challenge, error) {
+		return cli.sub.GetChallenges(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetChallenge(ctx context.Context, id int, opts ...Option) (*api.Challenge, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Challenge, error) {
+		return cli.sub.GetChallenge(id, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetChallengeRequirements(ctx context.Context, id int, opts ...Option) (*api.Requirements, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Requirements, error) {
+		return cli.sub.GetChallengeRequirements(id, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) PostChallenges(ctx context.Context, params *api.PostChallengesParams, opts ...Option) (*api.Challenge, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Challenge, error) {
+		return cli.sub.PostChallenges(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) PatchChallenge(ctx context.Context, id int, params *api.PatchChallengeParams, opts ...Option) (*api.Challenge, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Challenge, error) {
+		return cli.sub.PatchChallenge(id, params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteChallenge(ctx context.Context, id int, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteChallenge(id, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetChallengeFiles(ctx context.Context, id int, opts ...Option) ([]*api.File, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.File, error) {
+		return cli.sub.GetChallengeFiles(id, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetChallengeFlags(ctx context.Context, id int, opts ...Option) ([]*api.Flag, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Flag, error) {
+		return cli.sub.GetChallengeFlags(id, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetChallengeHints(ctx context.Context, id int, opts ...Option) ([]*api.Hint, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Hint, error) {
+		return cli.sub.GetChallengeHints(id, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetChallengeTags(ctx context.Context, id int, opts ...Option) ([]*api.Tag, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Tag, error) {
+		return cli.sub.GetChallengeTags(id, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) GetChallengeTopics(ctx context.Context, id int, opts ...Option) ([]*api.Topic, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Topic, error) {
+		return cli.sub.GetChallengeTopics(id, apiOptions(ctx)...)
+	})
+}
+
+// region flags
+
+func (cli *Client) PostFlags(ctx context.Context, params *api.PostFlagsParams, opts ...Option) (*api.Flag, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Flag, error) {
+		return cli.sub.PostFlags(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteFlag(ctx context.Context, id int, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteFlag(strconv.Itoa(id), apiOptions(ctx)...)
+	})
+}
+
+// region hints
+
+func (cli *Client) PostHints(ctx context.Context, params *api.PostHintsParams, opts ...Option) (*api.Hint, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Hint, error) {
+		return cli.sub.PostHints(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteHint(ctx context.Context, id int, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteHint(strconv.Itoa(id), apiOptions(ctx)...)
+	})
+}
+
+// region tags
+
+func (cli *Client) PostTags(ctx context.Context, params *api.PostTagsParams, opts ...Option) (*api.Tag, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Tag, error) {
+		return cli.sub.PostTags(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteTag(ctx context.Context, id int, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteTag(strconv.Itoa(id), apiOptions(ctx)...)
+	})
+}
+
+// region topics
+
+func (cli *Client) PostTopics(ctx context.Context, params *api.PostTopicsParams, opts ...Option) (*api.Topic, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Topic, error) {
+		return cli.sub.PostTopics(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteTopic(ctx context.Context, params *api.DeleteTopicArgs, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteTopic(params, apiOptions(ctx)...)
+	})
+}
+
+// region brackets
+
+func (cli *Client) GetBrackets(ctx context.Context, params *api.GetBracketsParams, opts ...Option) ([]*api.Bracket, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) ([]*api.Bracket, error) {
+		return cli.sub.GetBrackets(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) PostBrackets(ctx context.Context, params *api.PostBracketsParams, opts ...Option) (*api.Bracket, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Bracket, error) {
+		return cli.sub.PostBrackets(params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) PatchBrackets(ctx context.Context, id int, params *api.PatchBracketsParams, opts ...Option) (*api.Bracket, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Bracket, error) {
+		return cli.sub.PatchBrackets(id, params, apiOptions(ctx)...)
+	})
+}
+
+func (cli *Client) DeleteBrackets(ctx context.Context, id int, opts ...Option) error {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retry(ctx, opts, func(ctx context.Context) error {
+		return cli.sub.DeleteBrackets(id, apiOptions(ctx)...)
+	})
+}
+
+// region notifications
+
+func (cli *Client) PostNotifications(ctx context.Context, params *api.PostNotificationsParams, opts ...Option) (*api.Notification, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Notification, error) {
+		return cli.sub.PostNotifications(params, apiOptions(ctx)...)
+	})
+}
+
+// region tokens
+
+func (cli *Client) PostTokens(ctx context.Context, params *api.PostTokensParams, opts ...Option) (*api.Token, error) {
+	ctx, span := StartAPISpan(ctx, getTracer(opts...))
+	defer span.End()
+
+	LogAPICall(ctx)
+
+	return retryValue(ctx, opts, func(ctx context.Context) (*api.Token, error) {
+		return cli.sub.PostTokens(params, apiOptions(ctx)...)
+	})
 }
@@ -2,17 +2,19 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"syscall"
+	"time"
 
 	ctfdsetup "github.com/ctfer-io/ctfd-setup"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v3"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -31,545 +33,10 @@ func main() {
 	app := &cli.Command{
 		Name:  "CTFd Setup",
 		Usage: "Setup (and update) a CTFd instance from a fresh install or an already-existing one.",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.VersionFlag,
 			cli.HelpFlag,
-			&cli.StringFlag{
-				Name:     "file",
-				Usage:    "Configuration file to use for setting up CTFd. If let empty, will default the values and look for secrets in expected environment variables. For more info, refers to the documentation.",
-				Sources:  cli.EnvVars("FILE", "PLUGIN_FILE"),
-				Category: management,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:        "directory",
-				Aliases:     []string{"dir"},
-				Usage:       "The directory to parse from.",
-				Sources:     cli.EnvVars("DIRECTORY"),
-				Category:    management,
-				Destination: &ctfdsetup.Directory,
-				Local:       true,
-			},
-			&cli.StringFlag{
-				Name:     "url",
-				Usage:    "URL to reach the CTFd instance.",
-				Sources:  cli.EnvVars("URL", "PLUGIN_URL"),
-				Category: management,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "api_key",
-				Usage:    "The API key to use (for instance for a CI SA), used for updating a running CTFd instance.",
-				Sources:  cli.EnvVars("API_KEY", "PLUGIN_API_KEY"),
-				Category: management,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "log-level",
-				Usage:    "Use to specify the level of logging.",
-				Sources:  cli.EnvVars("LOG_LEVEL", "PLUGIN_LOG_LEVEL"),
-				Category: management,
-				Value:    "info",
-				Action: func(_ context.Context, _ *cli.Command, lvl string) error {
-					_, err := zapcore.ParseLevel(lvl)
-					return err
-				},
-				Local: true,
-			},
-			// Configuration file
-			// => Appearance
-			&cli.StringFlag{
-				Name:     "appearance.name",
-				Usage:    "The name of your CTF, displayed as is.",
-				Sources:  cli.EnvVars("APPEARANCE_NAME", "PLUGIN_APPEARANCE_NAME"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "appearance.description",
-				Usage:    "The description of your CTF, displayed as is.",
-				Sources:  cli.EnvVars("APPEARANCE_DESCRIPTION", "PLUGIN_APPEARANCE_DESCRIPTION"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "appearance.default_locale",
-				Usage:    "The default language for the users.",
-				Sources:  cli.EnvVars("APPEARANCE_DEFAULT_LOCALE", "PLUGIN_APPEARANCE_DEFAULT_LOCALE"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Theme
-			&cli.StringFlag{
-				Name:     "theme.logo",
-				Usage:    "The frontend logo. Provide a path to a locally-accessible file.",
-				Sources:  cli.EnvVars("THEME_LOGO", "PLUGIN_THEME_LOGO"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "theme.small_icon",
-				Usage:    "The frontend small icon. Provide a path to a locally-accessible file.",
-				Sources:  cli.EnvVars("THEME_SMALL_ICON", "PLUGIN_THEME_SMALL_ICON"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "theme.name",
-				Usage:    "The frontend theme name.",
-				Value:    "core",
-				Sources:  cli.EnvVars("THEME_NAME", "PLUGIN_THEME_NAME"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "theme.color",
-				Usage:    "The frontend theme color.",
-				Sources:  cli.EnvVars("THEME_COLOR", "PLUGIN_THEME_COLOR"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "theme.header",
-				Usage:    "The frontend header. Provide a path to a locally-accessible file.",
-				Sources:  cli.EnvVars("THEME_HEADER", "PLUGIN_THEME_HEADER"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "theme.footer",
-				Usage:    "The frontend footer. Provide a path to a locally-accessible file.",
-				Sources:  cli.EnvVars("THEME_FOOTER", "PLUGIN_THEME_FOOTER"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "theme.settings",
-				Usage:    "The frontend settings (JSON). Provide a path to a locally-accessible file.",
-				Sources:  cli.EnvVars("THEME_SETTINGS", "PLUGIN_THEME_SETTINGS"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Accounts
-			&cli.StringFlag{
-				Name:     "accounts.domain_whitelist",
-				Usage:    "The domain whitelist (a list separated by colons) to allow users to have email addresses from.",
-				Sources:  cli.EnvVars("ACCOUNTS_DOMAIN_WHITELIST", "PLUGIN_ACCOUNTS_DOMAIN_WHITELIST"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "accounts.domain_blacklist",
-				Usage:    "The domain blacklist (a list separated by colons) to block users to have email addresses from.",
-				Sources:  cli.EnvVars("ACCOUNTS_DOMAIN_BLACKLIST", "PLUGIN_ACCOUNTS_DOMAIN_BLACKLIST"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.BoolFlag{
-				Name:     "accounts.verify_emails",
-				Usage:    "Whether to verify emails once a user register or not.",
-				Value:    false,
-				Sources:  cli.EnvVars("ACCOUNTS_VERIFY_EMAILS", "PLUGIN_ACCOUNTS_VERIFY_EMAILS"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.BoolFlag{
-				Name:     "accounts.team_creation",
-				Usage:    "Whether to allow team creation by players or not.",
-				Sources:  cli.EnvVars("ACCOUNTS_TEAM_CREATION", "PLUGIN_ACCOUNTS_TEAM_CREATION"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.IntFlag{
-				Name:     "accounts.team_size",
-				Usage:    "Maximum size (number of players) in a team.",
-				Sources:  cli.EnvVars("ACCOUNTS_TEAM_SIZE", "PLUGIN_ACCOUNTS_TEAM_SIZE"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.IntFlag{
-				Name:     "accounts.password_min_length",
-				Usage:    "Minimal length of password.",
-				Sources:  cli.EnvVars("ACCOUNTS_PASSWORD_MIN_LENGTH", "PLUGIN_ACCOUNTS_PASSWORD_MIN_LENGTH"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.IntFlag{
-				Name:     "accounts.num_teams",
-				Usage:    "The total number of teams allowed.",
-				Sources:  cli.EnvVars("ACCOUNTS_NUM_TEAMS", "PLUGIN_ACCOUNTS_NUM_TEAMS"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.IntFlag{
-				Name:     "accounts.num_users",
-				Usage:    "The total number of users allowed.",
-				Sources:  cli.EnvVars("ACCOUNTS_NUM_USERS", "PLUGIN_ACCOUNTS_NUM_USERS"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "accounts.team_disbanding",
-				Usage:    "Whether to allow teams to be disbanded or not. Could be inactive_only or disabled.",
-				Sources:  cli.EnvVars("ACCOUNTS_TEAM_DISBANDING", "PLUGIN_ACCOUNTS_TEAM_DISBANDING"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.IntFlag{
-				Name:     "accounts.incorrect_submissions_per_minute",
-				Usage:    "Maximum number of invalid submissions per minute (per user/team). We suggest you use it as part of an anti-brute-force strategy (rate limiting).",
-				Sources:  cli.EnvVars("ACCOUNTS_INCORRECT_SUBMISSIONS_PER_MINUTE", "PLUGIN_ACCOUNTS_INCORRECT_SUBMISSIONS_PER_MINUTE"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.BoolFlag{
-				Name:     "accounts.name_changes",
-				Usage:    "Whether a user can change its name or not.",
-				Sources:  cli.EnvVars("ACCOUNTS_NAME_CHANGES", "PLUGIN_ACCOUNTS_NAME_CHANGES"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Challenges
-			&cli.BoolFlag{
-				Name:     "challenges.view_self_submissions",
-				Usage:    "Whether a player can see itw own previous submissions.",
-				Sources:  cli.EnvVars("CHALLENGES_VIEW_SELF_SUBMISSIONS", "PLUGIN_CHALLENGES_VIEW_SELF_SUBMISSIONS"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "challenges.max_attempts_behavior",
-				Usage:    "The behavior to adopt in case a player reached the submission rate limiting.",
-				Value:    "lockout",
-				Sources:  cli.EnvVars("CHALLENGES_MAX_ATTEMPTS_BEHAVIOR", "PLUGIN_CHALLENGES_MAX_ATTEMPTS_BEHAVIOR"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.IntFlag{
-				Name:     "challenges.max_attempts_timeout",
-				Usage:    "The duration of the submission rate limit for further submissions.",
-				Sources:  cli.EnvVars("CHALLENGES_MAX_ATTEMPTS_TIMEOUT", "PLUGIN_CHALLENGES_MAX_ATTEMPTS_TIMEOUT"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.BoolFlag{
-				Name:     "challenges.hints_free_public_access",
-				Usage:    "Control whether users must be logged in to see free hints.",
-				Sources:  cli.EnvVars("CHALLENGES_HINTS_FREE_PUBLIC_ACCESS", "PUBLIC_CHALLENGES_HINTS_FREE_PUBLIC_ACCESS"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "challenges.challenge_ratings",
-				Usage:    "Who can see and submit challenge ratings.",
-				Value:    "public",
-				Sources:  cli.EnvVars("CHALLENGES_CHALLENGE_RATINGS", "PUBLIC_CHALLENGES_CHALLENGE_RATINGS"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Pages
-			&cli.StringFlag{
-				Name:     "pages.robots_txt",
-				Usage:    "Define the /robots.txt file content, for web crawlers indexing. Provide a path to a locally-accessible file.",
-				Sources:  cli.EnvVars("PAGES_ROBOTS_TXT", "PLUGIN_PAGES_ROBOTS_TXT"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => MajorLeagueCyber
-			&cli.StringFlag{
-				Name:     "major_league_cyber.client_id",
-				Usage:    "The MajorLeagueCyber OAuth ClientID.",
-				Sources:  cli.EnvVars("MAJOR_LEAGUE_CYBER_CLIENT_ID", "PLUGIN_MAJOR_LEAGUE_CYBER_CLIENT_ID"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "major_league_cyber.client_secret",
-				Usage:    "The MajorLeagueCyber OAuth Client Secret.",
-				Sources:  cli.EnvVars("MAJOR_LEAGUE_CYBER_CLIENT_SECRET", "PLUGIN_MAJOR_LEAGUE_CYBER_CLIENT_SECRET"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Settings
-			&cli.StringFlag{
-				Name:     "settings.challenge_visibility",
-				Usage:    "The visibility for the challenges. Please refer to CTFd documentation (https://docs.ctfd.io/docs/settings/visibility-settings/).",
-				Value:    "public",
-				Sources:  cli.EnvVars("SETTINGS_CHALLENGE_VISIBILITY", "PLUGIN_SETTINGS_CHALLENGE_VISIBILITY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "settings.account_visibility",
-				Usage:    "The visibility for the accounts. Please refer to CTFd documentation (https://docs.ctfd.io/docs/settings/visibility-settings/).",
-				Value:    "public",
-				Sources:  cli.EnvVars("SETTINGS_ACCOUNT_VISIBILITY", "PLUGIN_SETTINGS_ACCOUNT_VISIBILITY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "settings.score_visibility",
-				Usage:    "The visibility for the scoreboard. Please refer to CTFd documentation (https://docs.ctfd.io/docs/settings/visibility-settings/).",
-				Value:    "public",
-				Sources:  cli.EnvVars("SETTINGS_SCORE_VISIBILITY", "PLUGIN_SETTINGS_SCORE_VISIBILITY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "settings.registration_visibility",
-				Usage:    "The visibility for the registration. Please refer to CTFd documentation (https://docs.ctfd.io/docs/settings/visibility-settings/).",
-				Value:    "public",
-				Sources:  cli.EnvVars("SETTINGS_REGISTRATION_VISIBILITY", "PLUGIN_SETTINGS_REGISTRATION_VISIBILITY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.BoolFlag{
-				Name:     "settings.paused",
-				Usage:    "Whether the CTFd is paused or not.",
-				Sources:  cli.EnvVars("SETTINGS_PAUSED", "PLUGIN_SETTINGS_PAUSED"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Security
-			&cli.BoolFlag{
-				Name:     "security.html_sanitization",
-				Usage:    "Whether to turn on HTML sanitization or not.",
-				Sources:  cli.EnvVars("SECURITY_HTML_SANITIZATION", "PLUGIN_SECURITY_HTML_SANITIZATION"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "security.registration_code",
-				Usage:    "The registration code (secret) to join the CTF.",
-				Sources:  cli.EnvVars("SECURITY_REGISTRATION_CODE", "PLUGIN_SECURITY_REGISTRATION_CODE"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Email
-			&cli.StringFlag{
-				Name:     "email.registration.subject",
-				Usage:    "The email registration subject of the mail.",
-				Sources:  cli.EnvVars("EMAIL_REGISTRATION_SUBJECT", "PLUGIN_EMAIL_REGISTRATION_SUBJECT"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.registration.body",
-				Usage:    "The email registration body of the mail.",
-				Sources:  cli.EnvVars("EMAIL_REGISTRATION_BODY", "PLUGIN_EMAIL_REGISTRATION_BODY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.confirmation.subject",
-				Usage:    "The email confirmation subject of the mail.",
-				Sources:  cli.EnvVars("EMAIL_CONFIRMATION_SUBJECT", "PLUGIN_EMAIL_CONFIRMATION_SUBJECT"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.confirmation.body",
-				Usage:    "The email confirmation body of the mail.",
-				Sources:  cli.EnvVars("EMAIL_CONFIRMATION_BODY", "PLUGIN_EMAIL_CONFIRMATION_BODY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.new_account.subject",
-				Usage:    "The email new_account subject of the mail.",
-				Sources:  cli.EnvVars("EMAIL_NEW_ACCOUNT_SUBJECT", "PLUGIN_EMAIL_NEW_ACCOUNT_SUBJECT"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.new_account.body",
-				Usage:    "The email new_account body of the mail.",
-				Sources:  cli.EnvVars("EMAIL_NEW_ACCOUNT_BODY", "PLUGIN_EMAIL_NEW_ACCOUNT_BODY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.password_reset.subject",
-				Usage:    "The email password_reset subject of the mail.",
-				Sources:  cli.EnvVars("EMAIL_PASSWORD_RESET_SUBJECT", "PLUGIN_EMAIL_PASSWORD_RESET_SUBJECT"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.password_reset.body",
-				Usage:    "The email password_reset body of the mail.",
-				Sources:  cli.EnvVars("EMAIL_PASSWORD_RESET_BODY", "PLUGIN_EMAIL_PASSWORD_RESET_BODY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.password_reset_confirmation.subject",
-				Usage:    "The email password_reset_confirmation subject of the mail.",
-				Sources:  cli.EnvVars("EMAIL_PASSWORD_RESET_CONFIRMATION_SUBJECT", "PLUGIN_EMAIL_PASSWORD_RESET_CONFIRMATION_SUBJECT"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.password_reset_confirmation.body",
-				Usage:    "The email password_reset_confirmation body of the mail.",
-				Sources:  cli.EnvVars("EMAIL_PASSWORD_RESET_CONFIRMATION_BODY", "PLUGIN_EMAIL_PASSWORD_RESET_CONFIRMATION_BODY"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.from",
-				Usage:    "The 'From:' to sent to mail with.",
-				Sources:  cli.EnvVars("EMAIL_MAIL_FROM", "PLUGIN_EMAIL_MAIL_FROM"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.server",
-				Usage:    "The mail server to use.",
-				Sources:  cli.EnvVars("EMAIL_MAIL_SERVER", "PLUGIN_EMAIL_MAIL_SERVER"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.port",
-				Usage:    "The mail server port to reach.",
-				Sources:  cli.EnvVars("EMAIL_MAIL_SERVER_PORT", "PLUGIN_EMAIL_MAIL_SERVER_PORT"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.username",
-				Usage:    "The username to log in to the mail server.",
-				Sources:  cli.EnvVars("EMAIL_USERNAME", "PLUGIN_EMAIL_USERNAME"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "email.password",
-				Usage:    "The password to log in to the mail server.",
-				Sources:  cli.EnvVars("EMAIL_PASSWORD", "PLUGIN_EMAIL_PASSWORD"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.BoolFlag{
-				Name:     "email.tls_ssl",
-				Usage:    "Whether to turn on TLS/SSL or not.",
-				Sources:  cli.EnvVars("EMAIL_TLS_SSL", "PLUGIN_EMAIL_TLS_SSL"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.BoolFlag{
-				Name:     "email.starttls",
-				Usage:    "Whether to turn on STARTTLS or not.",
-				Sources:  cli.EnvVars("EMAIL_STARTTLS", "PLUGIN_EMAIL_STARTTLS"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Time
-			&cli.StringFlag{
-				Name:     "time.start",
-				Usage:    "The start timestamp at which the CTFd will open.",
-				Sources:  cli.EnvVars("TIME_START", "PLUGIN_TIME_START"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "time.end",
-				Usage:    "The end timestamp at which the CTFd will close.",
-				Sources:  cli.EnvVars("TIME_END", "PLUGIN_TIME_END"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "time.freeze",
-				Usage:    "The freeze timestamp at which the CTFd will remain open but won't accept any further submissions.",
-				Sources:  cli.EnvVars("TIME_FREEZE", "PLUGIN_TIME_FREEZE"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.BoolFlag{
-				Name:     "time.view_after",
-				Usage:    "Whether allows users to view challenges after end or not.",
-				Sources:  cli.EnvVars("TIME_VIEW_AFTER", "PLUGIN_TIME_VIEW_AFTER"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Social
-			&cli.BoolFlag{
-				Name:     "social.shares",
-				Usage:    "Whether to enable users share they solved a challenge or not.",
-				Sources:  cli.EnvVars("SOCIAL_SHARES", "PLUGIN_SOCIAL_SHARES"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "social.template",
-				Usage:    "A template for social shares. Provide a path to a locally-accessible file.",
-				Sources:  cli.EnvVars("SOCIAL_TEMPLATE", "PUBLIC_SOCIAL_TEMPLATE"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => Legal
-			&cli.StringFlag{
-				Name:     "legal.tos.url",
-				Usage:    "The Terms of Services URL.",
-				Sources:  cli.EnvVars("LEGAL_TOS_URL", "PLUGIN_LEGAL_TOS_URL"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "legal.tos.content",
-				Usage:    "The Terms of Services content.",
-				Sources:  cli.EnvVars("LEGAL_TOS_CONTENT", "PLUGIN_LEGAL_TOS_CONTENT"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "legal.privacy_policy.url",
-				Usage:    "The Privacy Policy URL.",
-				Sources:  cli.EnvVars("LEGAL_PRIVACY_POLICY_URL", "PLUGIN_LEGAL_PRIVACY_POLICY_URL"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "legal.privacy_policy.content",
-				Usage:    "The Privacy Policy content.",
-				Sources:  cli.EnvVars("LEGAL_PRIVACY_POLICY_CONTENT", "PLUGIN_LEGAL_PRIVACY_POLICY_CONTENT"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => UserMode
-			&cli.StringFlag{
-				Name:     "mode",
-				Usage:    "The mode of your CTFd, either users or teams.",
-				Value:    "users",
-				Sources:  cli.EnvVars("MODE", "PLUGIN_MODE"),
-				Category: configuration,
-				Local:    true,
-			},
-			// => admin
-			&cli.StringFlag{
-				Name:     "admin.name",
-				Usage:    "The administrator name. Immutable, or need the administrator to change the CTFd data AND the configuration (CLI, varenv, file). Required.",
-				Sources:  cli.EnvVars("ADMIN_NAME", "PLUGIN_ADMIN_NAME"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "admin.email",
-				Usage:    "The administrator email address. Immutable, or need the administrator to change the CTFd data AND the configuration (CLI, varenv, file). Required.",
-				Sources:  cli.EnvVars("ADMIN_EMAIL", "PLUGIN_ADMIN_EMAIL"),
-				Category: configuration,
-				Local:    true,
-			},
-			&cli.StringFlag{
-				Name:     "admin.password",
-				Usage:    "The administrator password, recommended to use the varenvs. Immutable, or need the administrator to change the CTFd data AND the configuration (CLI, varenv, file). Required.",
-				Sources:  cli.EnvVars("ADMIN_PASSWORD", "PLUGIN_ADMIN_PASSWORD"),
-				Category: configuration,
-				Local:    true,
-			},
-		},
+		}, setupFlags()...),
 		Commands: []*cli.Command{
 			{
 				Name:  "schema",
@@ -591,6 +58,36 @@ func main() {
 					return os.WriteFile(o, append(schema, '\n'), 0644)
 				},
 			},
+			{
+				Name:   "plan",
+				Usage:  "Show the differences between the CTFd instance and the configuration, without applying them.",
+				Flags:  setupFlags(),
+				Action: plan,
+			},
+			{
+				Name:   "check",
+				Usage:  "Check whether the CTFd instance drifted from the configuration, without applying it. Exits with 0 if it matches, 2 if it drifted, or 1 on error.",
+				Flags:  setupFlags(),
+				Action: check,
+			},
+			{
+				Name:  "export",
+				Usage: "Generate a configuration file (and the files it refers to) from a running CTFd instance.",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "The output directory.",
+						Value:   ".",
+					},
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "The configuration file name, written in the output directory.",
+						Value: ".ctfd.yaml",
+					},
+				}, selectFlags(setupFlags(), "url", "api_key", "wait-timeout", "wait-backoff", "retry-attempts", "retry-backoff", "log-level", "admin.name", "admin.password")...),
+				Action: export,
+			},
 		},
 		Action: run,
 		Authors: []any{
@@ -616,77 +113,169 @@ func main() {
 }
 
 func run(ctx context.Context, cmd *cli.Command) error {
-	// Init OTel exporters
-	out, err := ctfdsetup.SetupOTelSDK(ctx, Version)
+	out, err := setupOTel(ctx, cmd)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := out.Shutdown(ctx); err != nil {
-			ctfdsetup.Log().Error(ctx, "shuttding down tracer provider",
-				zap.Error(err),
-			)
-		}
-	}()
+	defer shutdownOTel(ctx, out)
 
-	// Upsert logger so it takes its configuration (OTel + level)
-	log := ctfdsetup.UpsertLogger(out.LogProvider, cmd.String("log-level"))
-
-	logo, err := filePtr(cmd, "theme.logo")
+	conf, err := loadConfig(ctx, cmd)
 	if err != nil {
 		return err
 	}
-	smallIcon, err := filePtr(cmd, "theme.small_icon")
+
+	// Connect to CTFd
+	if !cmd.IsSet("url") {
+		return errors.New("url flag not set, is required")
+	}
+	return ctfdsetup.Setup(ctx,
+		cmd.String("url"),
+		cmd.String("api_key"),
+		conf,
+		setupOptions(cmd, out)...,
+	)
+}
+
+func plan(ctx context.Context, cmd *cli.Command) error {
+	diffs, err := diff(ctx, cmd)
 	if err != nil {
 		return err
 	}
-	header, err := filePtr(cmd, "theme.header")
-	if err != nil {
+
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintln(cmd.Root().Writer, "No changes, the CTFd instance matches the configuration.")
 		return err
 	}
-	footer, err := filePtr(cmd, "theme.footer")
+	for _, d := range diffs {
+		if _, err := fmt.Fprintln(cmd.Root().Writer, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func export(ctx context.Context, cmd *cli.Command) error {
+	out, err := setupOTel(ctx, cmd)
 	if err != nil {
 		return err
 	}
-	settings, err := filePtr(cmd, "theme.settings")
+	defer shutdownOTel(ctx, out)
+
+	if !cmd.IsSet("url") {
+		return errors.New("url flag not set, is required")
+	}
+	conf, err := ctfdsetup.Export(ctx,
+		cmd.String("url"),
+		cmd.String("api_key"),
+		ctfdsetup.Admin{
+			Name:     ctfdsetup.FromEnv{Content: cmd.String("admin.name")},
+			Password: ctfdsetup.FromEnv{Content: cmd.String("admin.password")},
+		},
+		setupOptions(cmd, out)...,
+	)
 	if err != nil {
 		return err
 	}
-	robotsTxt, err := filePtr(cmd, "pages.robots_txt")
-	if err != nil {
+
+	o := cmd.String("output")
+	if err := ctfdsetup.WriteConfig(conf, o, cmd.String("name")); err != nil {
 		return err
 	}
-	socialTpl, err := filePtr(cmd, "social.template")
+	ctfdsetup.Log().Info(ctx, "configuration exported",
+		zap.String("file", filepath.Join(o, cmd.String("name"))),
+	)
+	return nil
+}
+
+func check(ctx context.Context, cmd *cli.Command) error {
+	diffs, err := diff(ctx, cmd)
 	if err != nil {
 		return err
 	}
 
-	tos, err := filePtr(cmd, "legal.tos.content")
-	if err != nil {
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintln(cmd.Root().Writer, "No drift, the CTFd instance matches the configuration.")
 		return err
 	}
-	privpol, err := filePtr(cmd, "legal.privacy_policy.content")
-	if err != nil {
+	if _, err := fmt.Fprintf(cmd.Root().Writer, "Drift detected on %d key(s):\n", len(diffs)); err != nil {
 		return err
 	}
+	for _, d := range diffs {
+		if _, err := fmt.Fprintln(cmd.Root().Writer, d); err != nil {
+			return err
+		}
+	}
+	return cli.Exit("", 2)
+}
+
+// diff loads the configuration and compares it to the CTFd instance.
+func diff(ctx context.Context, cmd *cli.Command) ([]*ctfdsetup.Diff, error) {
+	out, err := setupOTel(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	defer shutdownOTel(ctx, out)
+
+	conf, err := loadConfig(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cmd.IsSet("url") {
+		return nil, errors.New("url flag not set, is required")
+	}
+	return ctfdsetup.Plan(ctx,
+		cmd.String("url"),
+		cmd.String("api_key"),
+		conf,
+		setupOptions(cmd, out)...,
+	)
+}
+
+// setupOptions returns the ctfd-setup options defined by the CLI flags.
+func setupOptions(cmd *cli.Command, out *ctfdsetup.OTelSetup) []ctfdsetup.Option {
+	opts := []ctfdsetup.Option{
+		ctfdsetup.WithTracerProvider(out.TracerProvider),
+	}
+	if timeout := cmd.Duration("wait-timeout"); timeout > 0 {
+		opts = append(opts, ctfdsetup.WithReadiness(timeout, cmd.Duration("wait-backoff")))
+	}
+	if attempts := cmd.Int("retry-attempts"); attempts > 1 {
+		opts = append(opts, ctfdsetup.WithRetry(attempts, cmd.Duration("retry-backoff")))
+	}
+	return opts
+}
+
+// setupOTel initializes the OTel exporters and upserts the logger
+// so it takes its configuration (OTel + level).
+func setupOTel(ctx context.Context, cmd *cli.Command) (*ctfdsetup.OTelSetup, error) {
+	out, err := ctfdsetup.SetupOTelSDK(ctx, Version)
+	if err != nil {
+		return nil, err
+	}
+	ctfdsetup.UpsertLogger(out.LogProvider, cmd.String("log-level"))
+	return out, nil
+}
+
+func shutdownOTel(ctx context.Context, out *ctfdsetup.OTelSetup) {
+	if err := out.Shutdown(ctx); err != nil {
+		ctfdsetup.Log().Error(ctx, "shuttding down tracer provider",
+			zap.Error(err),
+		)
+	}
+}
+
+// loadConfig reads the configuration file if any, then overrides it
+// with the CLI flags and validates it.
+func loadConfig(ctx context.Context, cmd *cli.Command) (*ctfdsetup.Config, error) {
 	conf := ctfdsetup.NewConfig()
 
 	// Read and unmarshal setup config file if any
 	if f := cmd.String("file"); f != "" {
-		log.Info(ctx, "loading configuration file", zap.String("file", f))
+		ctfdsetup.Log().Info(ctx, "loading configuration file", zap.String("file", f))
 
-		fd, err := os.Open(f)
-		if err != nil {
-			return errors.Wrapf(err, "opening configuration file %s", f)
-		}
-		defer func() {
-			_ = fd.Close()
-		}()
-
-		dec := yaml.NewDecoder(fd)
-		dec.KnownFields(true)
-		if err := dec.Decode(conf); err != nil {
-			return errors.Wrap(err, "unmarshalling configuration")
+		if err := ctfdsetup.LoadConfig(f, cmd.String("profile"), conf); err != nil {
+			return nil, errors.Wrap(err, "unmarshalling configuration")
 		}
 	}
 
@@ -697,13 +286,23 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	overrideForDefaultString(cmd, &conf.Appearance.Description, "appearance.description")
 	overrideForDefaultStringPtr(cmd, &conf.Appearance.DefaultLocale, "appearance.default_locale")
 
-	conf.Theme.Logo = logo
-	conf.Theme.SmallIcon = smallIcon
+	if err := overrideForDefaultFile(cmd, &conf.Theme.Logo, "theme.logo"); err != nil {
+		return nil, err
+	}
+	if err := overrideForDefaultFile(cmd, &conf.Theme.SmallIcon, "theme.small_icon"); err != nil {
+		return nil, err
+	}
 	overrideForDefaultString(cmd, &conf.Theme.Name, "theme.name")
 	overrideForDefaultString(cmd, &conf.Theme.Color, "theme.color")
-	conf.Theme.Header = header
-	conf.Theme.Footer = footer
-	conf.Theme.Settings = settings
+	if err := overrideForDefaultFile(cmd, &conf.Theme.Header, "theme.header"); err != nil {
+		return nil, err
+	}
+	if err := overrideForDefaultFile(cmd, &conf.Theme.Footer, "theme.footer"); err != nil {
+		return nil, err
+	}
+	if err := overrideForDefaultFile(cmd, &conf.Theme.Settings, "theme.settings"); err != nil {
+		return nil, err
+	}
 
 	overrideForDefaultStringPtr(cmd, &conf.Accounts.DomainWhitelist, "accounts.domain_whitelist")
 	overrideForDefaultStringPtr(cmd, &conf.Accounts.DomainBlacklist, "accounts.domain_blacklist")
@@ -722,11 +321,25 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	overrideForDefaultInt(cmd, &conf.Challenges.MaxAttemptsTimeout, "challenges.max_attempts_timeout")
 	overrideForDefaultBool(cmd, &conf.Challenges.HintsFreePublicAccess, "challenges.hints_free_public_access")
 	overrideForDefaultString(cmd, &conf.Challenges.ChallengeRatings, "challenges.challenge_ratings")
+	overrideForDefaultBool(cmd, &conf.Challenges.Prune, "challenges.prune")
+
+	overrideForDefaultBool(cmd, &conf.Brackets.Prune, "brackets.prune")
+
+	overrideForDefaultBool(cmd, &conf.Fields.Prune, "fields.prune")
+	overrideForDefaultBool(cmd, &conf.Fields.Force, "fields.force")
 
-	conf.Pages.RobotsTxt = robotsTxt
+	overrideForDefaultString(cmd, &conf.Users.PasswordsFile, "users.passwords_file")
+	overrideForDefaultString(cmd, &conf.Teams.PasswordsFile, "teams.passwords_file")
+
+	overrideForDefaultBool(cmd, &conf.Staff.Prune, "staff.prune")
+
+	if err := overrideForDefaultFile(cmd, &conf.Pages.RobotsTxt, "pages.robots_txt"); err != nil {
+		return nil, err
+	}
+	overrideForDefaultString(cmd, &conf.Pages.Prune, "pages.prune")
 
 	overrideForDefaultStringPtr(cmd, &conf.MajorLeagueCyber.ClientID, "major_league_cyber.client_id")
-	overrideForDefaultStringPtr(cmd, &conf.MajorLeagueCyber.ClientSecret, "major_league_cyber.client_secret")
+	overrideForDefaultFromEnvPtr(cmd, &conf.MajorLeagueCyber.ClientSecret, "major_league_cyber.client_secret")
 
 	overrideForDefaultString(cmd, &conf.Settings.ChallengeVisibility, "settings.challenge_visibility")
 	overrideForDefaultString(cmd, &conf.Settings.AccountVisibility, "settings.account_visibility")
@@ -735,7 +348,7 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	overrideForDefaultBoolPtr(cmd, &conf.Settings.Paused, "settings.paused")
 
 	overrideForDefaultBoolPtr(cmd, &conf.Security.HTMLSanitization, "security.html_sanitization")
-	overrideForDefaultStringPtr(cmd, &conf.Security.RegistrationCode, "security.registration_code")
+	overrideForDefaultFromEnvPtr(cmd, &conf.Security.RegistrationCode, "security.registration_code")
 
 	overrideForDefaultStringPtr(cmd, &conf.Email.Registration.Subject, "email.registration.subject")
 	overrideForDefaultStringPtr(cmd, &conf.Email.Registration.Body, "email.registration.body")
@@ -747,11 +360,13 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	overrideForDefaultStringPtr(cmd, &conf.Email.PasswordReset.Body, "email.password_reset.body")
 	overrideForDefaultStringPtr(cmd, &conf.Email.PasswordResetConfirmation.Subject, "email.password_reset_confirmation.subject")
 	overrideForDefaultStringPtr(cmd, &conf.Email.PasswordResetConfirmation.Body, "email.password_reset_confirmation.body")
-	overrideForDefaultStringPtr(cmd, &conf.Email.From, "email.mail_from")
-	overrideForDefaultStringPtr(cmd, &conf.Email.Server, "email.mail_server")
-	overrideForDefaultStringPtr(cmd, &conf.Email.Port, "email.mail_server_port")
+	overrideForDefaultStringPtr(cmd, &conf.Email.From, "email.from")
+	overrideForDefaultStringPtr(cmd, &conf.Email.Server, "email.server")
+	overrideForDefaultStringPtr(cmd, &conf.Email.Port, "email.port")
 	overrideForDefaultStringPtr(cmd, &conf.Email.Username, "email.username")
-	overrideForDefaultStringPtr(cmd, &conf.Email.Password, "email.password")
+	overrideForDefaultFromEnvPtr(cmd, &conf.Email.Password, "email.password")
+	overrideForDefaultBoolPtr(cmd, &conf.Email.TLS_SSL, "email.tls_ssl")
+	overrideForDefaultBoolPtr(cmd, &conf.Email.STARTTLS, "email.starttls")
 
 	overrideForDefaultStringPtr(cmd, &conf.Time.Start, "time.start")
 	overrideForDefaultStringPtr(cmd, &conf.Time.End, "time.end")
@@ -759,48 +374,794 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	overrideForDefaultBoolPtr(cmd, &conf.Time.ViewAfter, "time.view_after")
 
 	overrideForDefaultBoolPtr(cmd, &conf.Social.Shares, "social.shares")
-	conf.Social.Template = socialTpl
+	if err := overrideForDefaultFile(cmd, &conf.Social.Template, "social.template"); err != nil {
+		return nil, err
+	}
 
 	overrideForDefaultStringPtr(cmd, &conf.Legal.TOS.URL, "legal.tos.url")
-	conf.Legal.TOS.Content = tos
+	if err := overrideForDefaultFile(cmd, &conf.Legal.TOS.Content, "legal.tos.content"); err != nil {
+		return nil, err
+	}
 	overrideForDefaultStringPtr(cmd, &conf.Legal.PrivacyPolicy.URL, "legal.privacy_policy.url")
-	conf.Legal.PrivacyPolicy.Content = privpol
+	if err := overrideForDefaultFile(cmd, &conf.Legal.PrivacyPolicy.Content, "legal.privacy_policy.content"); err != nil {
+		return nil, err
+	}
 
 	overrideForDefaultString(cmd, &conf.Mode, "mode")
+	overrideForDefaultString(cmd, &conf.UploadsPolicy, "uploads_policy")
 
 	overrideForDefaultString(cmd, &conf.Admin.Name.Content, "admin.name")
 	overrideForDefaultString(cmd, &conf.Admin.Email.Content, "admin.email")
 	overrideForDefaultString(cmd, &conf.Admin.Password.Content, "admin.password")
+	overrideForDefaultFromEnvPtr(cmd, &conf.Admin.Rotate.Email, "admin.rotate.email")
+	overrideForDefaultFromEnvPtr(cmd, &conf.Admin.Rotate.Password, "admin.rotate.password")
+
+	overrideForDefaultString(cmd, &conf.Token.Output, "token.output")
+	overrideForDefaultString(cmd, &conf.Token.File, "token.file")
+	overrideForDefaultString(cmd, &conf.Token.Expiration, "token.expiration")
+	overrideForDefaultString(cmd, &conf.Token.Description, "token.description")
 
 	if err := conf.Validate(); err != nil {
-		return err
+		return nil, err
 	}
+	return conf, nil
+}
 
-	// Connect to CTFd
-	if !cmd.IsSet("url") {
-		return errors.New("url flag not set, is required")
+// selectFlags returns the flags matching the given names.
+func selectFlags(flags []cli.Flag, names ...string) []cli.Flag {
+	out := []cli.Flag{}
+	for _, f := range flags {
+		for _, n := range f.Names() {
+			if slices.Contains(names, n) {
+				out = append(out, f)
+				break
+			}
+		}
 	}
-	return ctfdsetup.Setup(ctx,
-		cmd.String("url"),
-		cmd.String("api_key"),
-		conf,
-		ctfdsetup.WithTracerProvider(out.TracerProvider),
-	)
+	return out
 }
 
-func filePtr(cmd *cli.Command, key string) (*ctfdsetup.File, error) {
+// setupFlags returns the flags shared by all commands that load
+// a configuration and reach a CTFd instance.
+func setupFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "file",
+			Usage:    "Configuration file to use for setting up CTFd. If let empty, will default the values and look for secrets in expected environment variables. For more info, refers to the documentation.",
+			Sources:  cli.EnvVars("FILE", "PLUGIN_FILE"),
+			Category: management,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "profile",
+			Usage:    "The profile of the configuration file to apply (e.g. staging), overlaying it.",
+			Sources:  cli.EnvVars("PROFILE", "PLUGIN_PROFILE"),
+			Category: management,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:        "directory",
+			Aliases:     []string{"dir"},
+			Usage:       "The directory to parse from.",
+			Sources:     cli.EnvVars("DIRECTORY"),
+			Category:    management,
+			Destination: &ctfdsetup.Directory,
+			Local:       true,
+		},
+		&cli.BoolFlag{
+			Name:        "strict-secrets",
+			Usage:       "Reject the secret files (from_secret_file) readable by anyone.",
+			Sources:     cli.EnvVars("STRICT_SECRETS", "PLUGIN_STRICT_SECRETS"),
+			Category:    management,
+			Destination: &ctfdsetup.StrictSecrets,
+			Local:       true,
+		},
+		&cli.StringFlag{
+			Name:        "age-identity",
+			Usage:       "The age identity file to decrypt the encrypted values of the configuration file with.",
+			Sources:     cli.EnvVars("AGE_IDENTITY", "PLUGIN_AGE_IDENTITY"),
+			Category:    management,
+			Destination: &ctfdsetup.AgeIdentity,
+			Local:       true,
+		},
+		&cli.StringFlag{
+			Name:     "url",
+			Usage:    "URL to reach the CTFd instance.",
+			Sources:  cli.EnvVars("URL", "PLUGIN_URL"),
+			Category: management,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "api_key",
+			Usage:    "The API key to use (for instance for a CI SA), used for updating a running CTFd instance.",
+			Sources:  cli.EnvVars("API_KEY", "PLUGIN_API_KEY"),
+			Category: management,
+			Local:    true,
+		},
+		&cli.DurationFlag{
+			Name:     "wait-timeout",
+			Usage:    "How long to wait for the CTFd instance to be ready (e.g. 2m), polling it until it serves the setup or login page. Does not wait if let empty.",
+			Sources:  cli.EnvVars("WAIT_TIMEOUT", "PLUGIN_WAIT_TIMEOUT"),
+			Category: management,
+			Local:    true,
+		},
+		&cli.DurationFlag{
+			Name:     "wait-backoff",
+			Usage:    "The delay between the first two readiness attempts, then doubled for each attempt.",
+			Sources:  cli.EnvVars("WAIT_BACKOFF", "PLUGIN_WAIT_BACKOFF"),
+			Category: management,
+			Value:    time.Second,
+			Local:    true,
+			Action: func(_ context.Context, _ *cli.Command, backoff time.Duration) error {
+				if backoff <= 0 {
+					return errors.New("wait-backoff must be positive")
+				}
+				return nil
+			},
+		},
+		&cli.IntFlag{
+			Name:     "retry-attempts",
+			Usage:    "How many times to attempt an API call that failed due to a transient error (HTTP 5xx or 429 response to an idempotent request). Does not retry if let to 1.",
+			Sources:  cli.EnvVars("RETRY_ATTEMPTS", "PLUGIN_RETRY_ATTEMPTS"),
+			Category: management,
+			Value:    1,
+			Local:    true,
+		},
+		&cli.DurationFlag{
+			Name:     "retry-backoff",
+			Usage:    "The delay between the first two attempts of an API call, then doubled for each attempt, with jitter.",
+			Sources:  cli.EnvVars("RETRY_BACKOFF", "PLUGIN_RETRY_BACKOFF"),
+			Category: management,
+			Value:    500 * time.Millisecond,
+			Local:    true,
+			Action: func(_ context.Context, _ *cli.Command, backoff time.Duration) error {
+				if backoff <= 0 {
+					return errors.New("retry-backoff must be positive")
+				}
+				return nil
+			},
+		},
+		&cli.StringFlag{
+			Name:     "log-level",
+			Usage:    "Use to specify the level of logging.",
+			Sources:  cli.EnvVars("LOG_LEVEL", "PLUGIN_LOG_LEVEL"),
+			Category: management,
+			Value:    "info",
+			Action: func(_ context.Context, _ *cli.Command, lvl string) error {
+				_, err := zapcore.ParseLevel(lvl)
+				return err
+			},
+			Local: true,
+		},
+		// Configuration file
+		// => Appearance
+		&cli.StringFlag{
+			Name:     "appearance.name",
+			Usage:    "The name of your CTF, displayed as is.",
+			Sources:  cli.EnvVars("APPEARANCE_NAME", "PLUGIN_APPEARANCE_NAME"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "appearance.description",
+			Usage:    "The description of your CTF, displayed as is.",
+			Sources:  cli.EnvVars("APPEARANCE_DESCRIPTION", "PLUGIN_APPEARANCE_DESCRIPTION"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "appearance.default_locale",
+			Usage:    "The default language for the users.",
+			Sources:  cli.EnvVars("APPEARANCE_DEFAULT_LOCALE", "PLUGIN_APPEARANCE_DEFAULT_LOCALE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Theme
+		&cli.StringFlag{
+			Name:     "theme.logo",
+			Usage:    "The frontend logo. Provide a path to a locally-accessible file.",
+			Sources:  cli.EnvVars("THEME_LOGO", "PLUGIN_THEME_LOGO"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "theme.small_icon",
+			Usage:    "The frontend small icon. Provide a path to a locally-accessible file.",
+			Sources:  cli.EnvVars("THEME_SMALL_ICON", "PLUGIN_THEME_SMALL_ICON"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "theme.name",
+			Usage:    "The frontend theme name.",
+			Value:    "core",
+			Sources:  cli.EnvVars("THEME_NAME", "PLUGIN_THEME_NAME"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "theme.color",
+			Usage:    "The frontend theme color.",
+			Sources:  cli.EnvVars("THEME_COLOR", "PLUGIN_THEME_COLOR"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "theme.header",
+			Usage:    "The frontend header. Provide a path to a locally-accessible file.",
+			Sources:  cli.EnvVars("THEME_HEADER", "PLUGIN_THEME_HEADER"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "theme.footer",
+			Usage:    "The frontend footer. Provide a path to a locally-accessible file.",
+			Sources:  cli.EnvVars("THEME_FOOTER", "PLUGIN_THEME_FOOTER"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "theme.settings",
+			Usage:    "The frontend settings (JSON). Provide a path to a locally-accessible file.",
+			Sources:  cli.EnvVars("THEME_SETTINGS", "PLUGIN_THEME_SETTINGS"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Accounts
+		&cli.StringFlag{
+			Name:     "accounts.domain_whitelist",
+			Usage:    "The domain whitelist (a list separated by colons) to allow users to have email addresses from.",
+			Sources:  cli.EnvVars("ACCOUNTS_DOMAIN_WHITELIST", "PLUGIN_ACCOUNTS_DOMAIN_WHITELIST"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "accounts.domain_blacklist",
+			Usage:    "The domain blacklist (a list separated by colons) to block users to have email addresses from.",
+			Sources:  cli.EnvVars("ACCOUNTS_DOMAIN_BLACKLIST", "PLUGIN_ACCOUNTS_DOMAIN_BLACKLIST"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "accounts.verify_emails",
+			Usage:    "Whether to verify emails once a user register or not.",
+			Value:    false,
+			Sources:  cli.EnvVars("ACCOUNTS_VERIFY_EMAILS", "PLUGIN_ACCOUNTS_VERIFY_EMAILS"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "accounts.team_creation",
+			Usage:    "Whether to allow team creation by players or not.",
+			Sources:  cli.EnvVars("ACCOUNTS_TEAM_CREATION", "PLUGIN_ACCOUNTS_TEAM_CREATION"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.IntFlag{
+			Name:     "accounts.team_size",
+			Usage:    "Maximum size (number of players) in a team.",
+			Sources:  cli.EnvVars("ACCOUNTS_TEAM_SIZE", "PLUGIN_ACCOUNTS_TEAM_SIZE"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.IntFlag{
+			Name:     "accounts.password_min_length",
+			Usage:    "Minimal length of password.",
+			Sources:  cli.EnvVars("ACCOUNTS_PASSWORD_MIN_LENGTH", "PLUGIN_ACCOUNTS_PASSWORD_MIN_LENGTH"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.IntFlag{
+			Name:     "accounts.num_teams",
+			Usage:    "The total number of teams allowed.",
+			Sources:  cli.EnvVars("ACCOUNTS_NUM_TEAMS", "PLUGIN_ACCOUNTS_NUM_TEAMS"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.IntFlag{
+			Name:     "accounts.num_users",
+			Usage:    "The total number of users allowed.",
+			Sources:  cli.EnvVars("ACCOUNTS_NUM_USERS", "PLUGIN_ACCOUNTS_NUM_USERS"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "accounts.team_disbanding",
+			Usage:    "Whether to allow teams to be disbanded or not. Could be inactive_only or disabled.",
+			Sources:  cli.EnvVars("ACCOUNTS_TEAM_DISBANDING", "PLUGIN_ACCOUNTS_TEAM_DISBANDING"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.IntFlag{
+			Name:     "accounts.incorrect_submissions_per_minute",
+			Usage:    "Maximum number of invalid submissions per minute (per user/team). We suggest you use it as part of an anti-brute-force strategy (rate limiting).",
+			Sources:  cli.EnvVars("ACCOUNTS_INCORRECT_SUBMISSIONS_PER_MINUTE", "PLUGIN_ACCOUNTS_INCORRECT_SUBMISSIONS_PER_MINUTE"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "accounts.name_changes",
+			Usage:    "Whether a user can change its name or not.",
+			Sources:  cli.EnvVars("ACCOUNTS_NAME_CHANGES", "PLUGIN_ACCOUNTS_NAME_CHANGES"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Challenges
+		&cli.BoolFlag{
+			Name:     "challenges.view_self_submissions",
+			Usage:    "Whether a player can see itw own previous submissions.",
+			Sources:  cli.EnvVars("CHALLENGES_VIEW_SELF_SUBMISSIONS", "PLUGIN_CHALLENGES_VIEW_SELF_SUBMISSIONS"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "challenges.max_attempts_behavior",
+			Usage:    "The behavior to adopt in case a player reached the submission rate limiting.",
+			Value:    "lockout",
+			Sources:  cli.EnvVars("CHALLENGES_MAX_ATTEMPTS_BEHAVIOR", "PLUGIN_CHALLENGES_MAX_ATTEMPTS_BEHAVIOR"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.IntFlag{
+			Name:     "challenges.max_attempts_timeout",
+			Usage:    "The duration of the submission rate limit for further submissions.",
+			Sources:  cli.EnvVars("CHALLENGES_MAX_ATTEMPTS_TIMEOUT", "PLUGIN_CHALLENGES_MAX_ATTEMPTS_TIMEOUT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "challenges.hints_free_public_access",
+			Usage:    "Control whether users must be logged in to see free hints.",
+			Sources:  cli.EnvVars("CHALLENGES_HINTS_FREE_PUBLIC_ACCESS", "PUBLIC_CHALLENGES_HINTS_FREE_PUBLIC_ACCESS"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "challenges.challenge_ratings",
+			Usage:    "Who can see and submit challenge ratings.",
+			Value:    "public",
+			Sources:  cli.EnvVars("CHALLENGES_CHALLENGE_RATINGS", "PUBLIC_CHALLENGES_CHALLENGE_RATINGS"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "challenges.prune",
+			Usage:    "Whether to delete the challenges created by ctfd-setup that are removed from the list.",
+			Sources:  cli.EnvVars("CHALLENGES_PRUNE", "PLUGIN_CHALLENGES_PRUNE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Brackets
+		&cli.BoolFlag{
+			Name:     "brackets.prune",
+			Usage:    "Whether to delete the brackets configured by ctfd-setup that are removed from the list.",
+			Sources:  cli.EnvVars("BRACKETS_PRUNE", "PLUGIN_BRACKETS_PRUNE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Fields
+		&cli.BoolFlag{
+			Name:     "fields.prune",
+			Usage:    "Whether to delete the custom fields configured by ctfd-setup that are removed from the lists.",
+			Sources:  cli.EnvVars("FIELDS_PRUNE", "PLUGIN_FIELDS_PRUNE"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "fields.force",
+			Usage:    "Whether to delete custom fields even if players already filled them, dropping their values.",
+			Sources:  cli.EnvVars("FIELDS_FORCE", "PLUGIN_FIELDS_FORCE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Users
+		&cli.StringFlag{
+			Name:     "users.passwords_file",
+			Usage:    "The local file the generated users passwords are written to, for distribution.",
+			Value:    "users-passwords.csv",
+			Sources:  cli.EnvVars("USERS_PASSWORDS_FILE", "PLUGIN_USERS_PASSWORDS_FILE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Teams
+		&cli.StringFlag{
+			Name:     "teams.passwords_file",
+			Usage:    "The local file the generated teams passwords are written to, for distribution.",
+			Value:    "teams-passwords.csv",
+			Sources:  cli.EnvVars("TEAMS_PASSWORDS_FILE", "PLUGIN_TEAMS_PASSWORDS_FILE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Staff
+		&cli.BoolFlag{
+			Name:     "staff.prune",
+			Usage:    "Whether to delete the staff accounts configured by ctfd-setup that are removed from the list.",
+			Sources:  cli.EnvVars("STAFF_PRUNE", "PLUGIN_STAFF_PRUNE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Pages
+		&cli.StringFlag{
+			Name:     "pages.robots_txt",
+			Usage:    "Define the /robots.txt file content, for web crawlers indexing. Provide a path to a locally-accessible file.",
+			Sources:  cli.EnvVars("PAGES_ROBOTS_TXT", "PLUGIN_PAGES_ROBOTS_TXT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "pages.prune",
+			Usage:    "Which pages to delete when not configured: the ones created by ctfd-setup (managed), all of them, or none.",
+			Value:    "managed",
+			Sources:  cli.EnvVars("PAGES_PRUNE", "PLUGIN_PAGES_PRUNE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => MajorLeagueCyber
+		&cli.StringFlag{
+			Name:     "major_league_cyber.client_id",
+			Usage:    "The MajorLeagueCyber OAuth ClientID.",
+			Sources:  cli.EnvVars("MAJOR_LEAGUE_CYBER_CLIENT_ID", "PLUGIN_MAJOR_LEAGUE_CYBER_CLIENT_ID"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "major_league_cyber.client_secret",
+			Usage:    "The MajorLeagueCyber OAuth Client Secret.",
+			Sources:  cli.EnvVars("MAJOR_LEAGUE_CYBER_CLIENT_SECRET", "PLUGIN_MAJOR_LEAGUE_CYBER_CLIENT_SECRET"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Settings
+		&cli.StringFlag{
+			Name:     "settings.challenge_visibility",
+			Usage:    "The visibility for the challenges. Please refer to CTFd documentation (https://docs.ctfd.io/docs/settings/visibility-settings/).",
+			Value:    "public",
+			Sources:  cli.EnvVars("SETTINGS_CHALLENGE_VISIBILITY", "PLUGIN_SETTINGS_CHALLENGE_VISIBILITY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "settings.account_visibility",
+			Usage:    "The visibility for the accounts. Please refer to CTFd documentation (https://docs.ctfd.io/docs/settings/visibility-settings/).",
+			Value:    "public",
+			Sources:  cli.EnvVars("SETTINGS_ACCOUNT_VISIBILITY", "PLUGIN_SETTINGS_ACCOUNT_VISIBILITY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "settings.score_visibility",
+			Usage:    "The visibility for the scoreboard. Please refer to CTFd documentation (https://docs.ctfd.io/docs/settings/visibility-settings/).",
+			Value:    "public",
+			Sources:  cli.EnvVars("SETTINGS_SCORE_VISIBILITY", "PLUGIN_SETTINGS_SCORE_VISIBILITY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "settings.registration_visibility",
+			Usage:    "The visibility for the registration. Please refer to CTFd documentation (https://docs.ctfd.io/docs/settings/visibility-settings/).",
+			Value:    "public",
+			Sources:  cli.EnvVars("SETTINGS_REGISTRATION_VISIBILITY", "PLUGIN_SETTINGS_REGISTRATION_VISIBILITY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "settings.paused",
+			Usage:    "Whether the CTFd is paused or not.",
+			Sources:  cli.EnvVars("SETTINGS_PAUSED", "PLUGIN_SETTINGS_PAUSED"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Security
+		&cli.BoolFlag{
+			Name:     "security.html_sanitization",
+			Usage:    "Whether to turn on HTML sanitization or not.",
+			Sources:  cli.EnvVars("SECURITY_HTML_SANITIZATION", "PLUGIN_SECURITY_HTML_SANITIZATION"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "security.registration_code",
+			Usage:    "The registration code (secret) to join the CTF.",
+			Sources:  cli.EnvVars("SECURITY_REGISTRATION_CODE", "PLUGIN_SECURITY_REGISTRATION_CODE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Email
+		&cli.StringFlag{
+			Name:     "email.registration.subject",
+			Usage:    "The email registration subject of the mail.",
+			Sources:  cli.EnvVars("EMAIL_REGISTRATION_SUBJECT", "PLUGIN_EMAIL_REGISTRATION_SUBJECT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.registration.body",
+			Usage:    "The email registration body of the mail.",
+			Sources:  cli.EnvVars("EMAIL_REGISTRATION_BODY", "PLUGIN_EMAIL_REGISTRATION_BODY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.confirmation.subject",
+			Usage:    "The email confirmation subject of the mail.",
+			Sources:  cli.EnvVars("EMAIL_CONFIRMATION_SUBJECT", "PLUGIN_EMAIL_CONFIRMATION_SUBJECT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.confirmation.body",
+			Usage:    "The email confirmation body of the mail.",
+			Sources:  cli.EnvVars("EMAIL_CONFIRMATION_BODY", "PLUGIN_EMAIL_CONFIRMATION_BODY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.new_account.subject",
+			Usage:    "The email new_account subject of the mail.",
+			Sources:  cli.EnvVars("EMAIL_NEW_ACCOUNT_SUBJECT", "PLUGIN_EMAIL_NEW_ACCOUNT_SUBJECT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.new_account.body",
+			Usage:    "The email new_account body of the mail.",
+			Sources:  cli.EnvVars("EMAIL_NEW_ACCOUNT_BODY", "PLUGIN_EMAIL_NEW_ACCOUNT_BODY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.password_reset.subject",
+			Usage:    "The email password_reset subject of the mail.",
+			Sources:  cli.EnvVars("EMAIL_PASSWORD_RESET_SUBJECT", "PLUGIN_EMAIL_PASSWORD_RESET_SUBJECT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.password_reset.body",
+			Usage:    "The email password_reset body of the mail.",
+			Sources:  cli.EnvVars("EMAIL_PASSWORD_RESET_BODY", "PLUGIN_EMAIL_PASSWORD_RESET_BODY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.password_reset_confirmation.subject",
+			Usage:    "The email password_reset_confirmation subject of the mail.",
+			Sources:  cli.EnvVars("EMAIL_PASSWORD_RESET_CONFIRMATION_SUBJECT", "PLUGIN_EMAIL_PASSWORD_RESET_CONFIRMATION_SUBJECT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.password_reset_confirmation.body",
+			Usage:    "The email password_reset_confirmation body of the mail.",
+			Sources:  cli.EnvVars("EMAIL_PASSWORD_RESET_CONFIRMATION_BODY", "PLUGIN_EMAIL_PASSWORD_RESET_CONFIRMATION_BODY"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.from",
+			Usage:    "The 'From:' to sent to mail with.",
+			Sources:  cli.EnvVars("EMAIL_MAIL_FROM", "PLUGIN_EMAIL_MAIL_FROM"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.server",
+			Usage:    "The mail server to use.",
+			Sources:  cli.EnvVars("EMAIL_MAIL_SERVER", "PLUGIN_EMAIL_MAIL_SERVER"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.port",
+			Usage:    "The mail server port to reach.",
+			Sources:  cli.EnvVars("EMAIL_MAIL_SERVER_PORT", "PLUGIN_EMAIL_MAIL_SERVER_PORT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.username",
+			Usage:    "The username to log in to the mail server.",
+			Sources:  cli.EnvVars("EMAIL_USERNAME", "PLUGIN_EMAIL_USERNAME"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "email.password",
+			Usage:    "The password to log in to the mail server.",
+			Sources:  cli.EnvVars("EMAIL_PASSWORD", "PLUGIN_EMAIL_PASSWORD"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "email.tls_ssl",
+			Usage:    "Whether to turn on TLS/SSL or not.",
+			Sources:  cli.EnvVars("EMAIL_TLS_SSL", "PLUGIN_EMAIL_TLS_SSL"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "email.starttls",
+			Usage:    "Whether to turn on STARTTLS or not.",
+			Sources:  cli.EnvVars("EMAIL_STARTTLS", "PLUGIN_EMAIL_STARTTLS"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Time
+		&cli.StringFlag{
+			Name:     "time.start",
+			Usage:    "The start timestamp at which the CTFd will open.",
+			Sources:  cli.EnvVars("TIME_START", "PLUGIN_TIME_START"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "time.end",
+			Usage:    "The end timestamp at which the CTFd will close.",
+			Sources:  cli.EnvVars("TIME_END", "PLUGIN_TIME_END"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "time.freeze",
+			Usage:    "The freeze timestamp at which the CTFd will remain open but won't accept any further submissions.",
+			Sources:  cli.EnvVars("TIME_FREEZE", "PLUGIN_TIME_FREEZE"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.BoolFlag{
+			Name:     "time.view_after",
+			Usage:    "Whether allows users to view challenges after end or not.",
+			Sources:  cli.EnvVars("TIME_VIEW_AFTER", "PLUGIN_TIME_VIEW_AFTER"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Social
+		&cli.BoolFlag{
+			Name:     "social.shares",
+			Usage:    "Whether to enable users share they solved a challenge or not.",
+			Sources:  cli.EnvVars("SOCIAL_SHARES", "PLUGIN_SOCIAL_SHARES"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "social.template",
+			Usage:    "A template for social shares. Provide a path to a locally-accessible file.",
+			Sources:  cli.EnvVars("SOCIAL_TEMPLATE", "PUBLIC_SOCIAL_TEMPLATE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Legal
+		&cli.StringFlag{
+			Name:     "legal.tos.url",
+			Usage:    "The Terms of Services URL.",
+			Sources:  cli.EnvVars("LEGAL_TOS_URL", "PLUGIN_LEGAL_TOS_URL"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "legal.tos.content",
+			Usage:    "The Terms of Services content.",
+			Sources:  cli.EnvVars("LEGAL_TOS_CONTENT", "PLUGIN_LEGAL_TOS_CONTENT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "legal.privacy_policy.url",
+			Usage:    "The Privacy Policy URL.",
+			Sources:  cli.EnvVars("LEGAL_PRIVACY_POLICY_URL", "PLUGIN_LEGAL_PRIVACY_POLICY_URL"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "legal.privacy_policy.content",
+			Usage:    "The Privacy Policy content.",
+			Sources:  cli.EnvVars("LEGAL_PRIVACY_POLICY_CONTENT", "PLUGIN_LEGAL_PRIVACY_POLICY_CONTENT"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => UserMode
+		&cli.StringFlag{
+			Name:     "mode",
+			Usage:    "The mode of your CTFd, either users or teams.",
+			Value:    "users",
+			Sources:  cli.EnvVars("MODE", "PLUGIN_MODE"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Uploads
+		&cli.StringFlag{
+			Name:     "uploads_policy",
+			Usage:    "What to do with the files uploaded by ctfd-setup that are removed from the uploads: keep or prune them. Files uploaded by other means are never pruned.",
+			Value:    "keep",
+			Sources:  cli.EnvVars("UPLOADS_POLICY", "PLUGIN_UPLOADS_POLICY"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => admin
+		&cli.StringFlag{
+			Name:     "admin.name",
+			Usage:    "The administrator name. Immutable, or need the administrator to change the CTFd data AND the configuration (CLI, varenv, file). Required.",
+			Sources:  cli.EnvVars("ADMIN_NAME", "PLUGIN_ADMIN_NAME"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "admin.email",
+			Usage:    "The administrator email address. Change it through admin.rotate.email. Required.",
+			Sources:  cli.EnvVars("ADMIN_EMAIL", "PLUGIN_ADMIN_EMAIL"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "admin.password",
+			Usage:    "The administrator password, recommended to use the varenvs. Change it through admin.rotate.password. Required.",
+			Sources:  cli.EnvVars("ADMIN_PASSWORD", "PLUGIN_ADMIN_PASSWORD"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "admin.rotate.email",
+			Usage:    "The new administrator email address to rotate to. Once rotated, it can replace the administrator email address.",
+			Sources:  cli.EnvVars("ADMIN_ROTATE_EMAIL", "PLUGIN_ADMIN_ROTATE_EMAIL"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "admin.rotate.password",
+			Usage:    "The new administrator password to rotate to, recommended to use the varenvs. It is checked by logging in before reporting success. Once rotated, it can replace the administrator password.",
+			Sources:  cli.EnvVars("ADMIN_ROTATE_PASSWORD", "PLUGIN_ADMIN_ROTATE_PASSWORD"),
+			Category: configuration,
+			Local:    true,
+		},
+		// => Token
+		&cli.StringFlag{
+			Name:     "token.output",
+			Usage:    "Where to write the API token bootstrapped after a bare setup: file (reused by later runs), stdout or github (step output named api_key). Does not bootstrap one if let empty.",
+			Sources:  cli.EnvVars("TOKEN_OUTPUT", "PLUGIN_TOKEN_OUTPUT"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "token.file",
+			Usage:    "The local file to write the bootstrapped API token to, then read it from on later runs.",
+			Value:    ".ctfd-token",
+			Sources:  cli.EnvVars("TOKEN_FILE", "PLUGIN_TOKEN_FILE"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "token.expiration",
+			Usage:    "The bootstrapped API token expiration date (e.g. 2025-01-01). CTFd defaults it to 30 days if let empty.",
+			Sources:  cli.EnvVars("TOKEN_EXPIRATION", "PLUGIN_TOKEN_EXPIRATION"),
+			Category: configuration,
+			Local:    true,
+		},
+		&cli.StringFlag{
+			Name:     "token.description",
+			Usage:    "The bootstrapped API token description, displayed in the administrator settings.",
+			Value:    "ctfd-setup automation",
+			Sources:  cli.EnvVars("TOKEN_DESCRIPTION", "PLUGIN_TOKEN_DESCRIPTION"),
+			Category: configuration,
+			Local:    true,
+		},
+	}
+}
+
+func overrideForDefaultFile(cmd *cli.Command, dst **ctfdsetup.File, key string) error {
 	fp := cmd.String(key)
-	if fp == "" {
-		return &ctfdsetup.File{}, nil
+	if !cmd.IsSet(key) || fp == "" { // avoid empty paths
+		// Don't change anything, it remains as it is
+		return nil
 	}
 	content, err := os.ReadFile(fp)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to open file %s", fp)
+		return errors.Wrapf(err, "failed to open file %s", fp)
 	}
-	return &ctfdsetup.File{
+	*dst = &ctfdsetup.File{
 		Name:    filepath.Base(fp),
-		Content: []byte(content),
-	}, nil
+		Content: content,
+	}
+	return nil
 }
 
 func overrideForDefaultString(cmd *cli.Command, dst *string, key string) {
@@ -819,6 +1180,14 @@ func overrideForDefaultStringPtr(cmd *cli.Command, dst **string, key string) {
 	// Don't change anything, it remains as it is
 }
 
+func overrideForDefaultFromEnvPtr(cmd *cli.Command, dst **ctfdsetup.FromEnv, key string) {
+	str := cmd.String(key)
+	if cmd.IsSet(key) && str != "" { // avoid empty strings
+		*dst = &ctfdsetup.FromEnv{Content: str}
+	}
+	// Don't change anything, it remains as it is
+}
+
 func overrideForDefaultBool(cmd *cli.Command, dst *bool, key string) {
 	b := cmd.Bool(key)
 	if cmd.IsSet(key) && b { // avoid false values
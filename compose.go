@@ -0,0 +1,489 @@
+package ctfdsetup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// extendsKey is the configuration key of the files a configuration extends.
+	extendsKey = "extends"
+	// profilesKey is the configuration key of the profiles overlays.
+	profilesKey = "profiles"
+	// includeTag is the YAML tag of a value defined by the content of a file.
+	includeTag = "!include"
+)
+
+// mergeKeys are the lists merged by item, identified by the first of their
+// attributes that is defined. Other lists are replaced.
+var mergeKeys = map[string][]string{
+	"pages.additional": {"route"},
+	"uploads":          {"location"},
+	"brackets.list":    {"name"},
+	"fields.users":     {"name"},
+	"fields.teams":     {"name"},
+	"users.list":       {"email"},
+	"teams.list":       {"name"},
+	"staff.list":       {"email"},
+	"challenges.list":  {"key", "name"},
+	"notifications":    {"key", "title"},
+}
+
+// pathKeys are the configuration keys whose values are paths to files.
+var pathKeys = []string{
+	"from_file",
+	"from_secret_file",
+	"from_ctfcli",
+}
+
+// LoadConfig reads a YAML configuration file, composes it with the files it
+// extends and includes, interpolates the environment variables it refers to,
+// then decodes it into conf. Unknown fields are rejected.
+//
+// The files listed under "extends" are merged in order, then the configuration
+// is merged on top of them: maps are merged by key, lists in mergeKeys by item,
+// and other values are replaced.
+// A value tagged "!include" is replaced by the content of the file.
+//
+// Paths in extended and included files resolve from the file declaring them.
+// Errors point to the source file and line.
+//
+// If a profile is given, its overlay from "profiles" is then merged on top of
+// the configuration, with the same rules. The environment variables required by
+// the other profiles are not.
+func LoadConfig(path, profile string, conf *Config) error {
+	base, err := filepath.Abs(Directory)
+	if err != nil {
+		return err
+	}
+	l := &loader{
+		base: base,
+		in:   &interpolator{},
+	}
+	node, err := l.load(path, true, nil)
+	if err != nil {
+		return l.locate(err)
+	}
+	if node == nil {
+		return io.EOF
+	}
+	if err := applyProfile(node, profile); err != nil {
+		return l.locate(err)
+	}
+	if err := l.in.err(node); err != nil {
+		return l.locate(err)
+	}
+	l.in.resolve(node, reflect.TypeOf(conf))
+	return l.locate(decodeNode(node, conf))
+}
+
+// applyProfile merges the overlay of the profile on top of the configuration,
+// and removes the profiles from it. All profiles must only define configuration
+// fields.
+func applyProfile(doc *yaml.Node, profile string) error {
+	node := doc.Content[0]
+	i := mappingIndex(node, profilesKey)
+	if i < 0 {
+		if profile != "" {
+			return fmt.Errorf("profile %s is not defined, no profiles are", profile)
+		}
+		return nil
+	}
+	profiles := node.Content[i+1]
+	node.Content = slices.Delete(node.Content, i, i+2)
+	if profiles.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: %s must be a map of profiles", profiles.Line, profilesKey)
+	}
+
+	var merr error
+	for i := 1; i < len(profiles.Content); i += 2 {
+		merr = multierr.Append(merr, knownFields(profiles.Content[i], reflect.TypeOf(Config{})))
+	}
+	if merr != nil || profile == "" {
+		return merr
+	}
+
+	j := mappingIndex(profiles, profile)
+	if j < 0 {
+		return fmt.Errorf("line %d: profile %s is not defined", profiles.Line, profile)
+	}
+	doc.Content[0] = merge(node, profiles.Content[j+1], "")
+	return nil
+}
+
+// decodeNode decodes a YAML document into conf. Unknown fields are rejected.
+func decodeNode(node *yaml.Node, conf *Config) error {
+	if err := knownFields(node, reflect.TypeOf(conf)); err != nil {
+		return err
+	}
+	return node.Decode(conf)
+}
+
+// source is a file loaded as part of a configuration, its lines being numbered
+// after the ones of the previously loaded files.
+type source struct {
+	path   string
+	offset int
+	lines  int
+}
+
+// loader composes a configuration from its files.
+type loader struct {
+	// base is the absolute Directory the paths are relative to
+	base    string
+	in      *interpolator
+	sources []source
+}
+
+// load reads a file and composes it with the files it includes, then, for a
+// configuration file, with the ones it extends.
+// Relative paths are resolved from the file, unless it is the root configuration
+// file whose paths are relative to Directory.
+// It returns nil if the file is empty.
+func (l *loader) load(path string, root bool, stack []string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(stack, abs) {
+		return nil, fmt.Errorf("circular composition: %s", strings.Join(append(stack, abs), " -> "))
+	}
+	stack = append(stack, abs)
+	dir := filepath.Dir(abs)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	src := source{
+		path:  path,
+		lines: strings.Count(string(b), "\n") + 1,
+	}
+	if n := len(l.sources); n != 0 {
+		src.offset = l.sources[n-1].offset + l.sources[n-1].lines
+	}
+	l.sources = append(l.sources, src)
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(b, doc); err != nil {
+		// Lines are not shifted yet
+		return nil, errors.New(linePattern.ReplaceAllString(err.Error(), path+":$1"))
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	shiftLines(doc, src.offset, map[*yaml.Node]struct{}{})
+	l.in.walk(doc)
+
+	// Rebase the paths before including, as included files rebase their own
+	if !root {
+		l.rebase(doc, dir)
+	}
+	var merr error
+	if err := l.include(doc, dir, stack); err != nil {
+		merr = multierr.Append(merr, err)
+	}
+
+	node := doc.Content[0]
+	extends, err := l.extends(node, dir, stack)
+	if err != nil {
+		merr = multierr.Append(merr, err)
+	}
+	if merr != nil {
+		return nil, merr
+	}
+
+	var out *yaml.Node
+	for _, ext := range extends {
+		if out == nil {
+			out = ext
+			continue
+		}
+		out = merge(out, ext, "")
+	}
+	if out == nil {
+		return doc, nil
+	}
+	doc.Content[0] = merge(out, node, "")
+	return doc, nil
+}
+
+// extends loads the files the configuration extends, and removes them from it.
+func (l *loader) extends(node *yaml.Node, dir string, stack []string) ([]*yaml.Node, error) {
+	i := mappingIndex(node, extendsKey)
+	if i < 0 {
+		return nil, nil
+	}
+	v := node.Content[i+1]
+	node.Content = slices.Delete(node.Content, i, i+2)
+
+	paths := []*yaml.Node{v}
+	if v.Kind == yaml.SequenceNode {
+		paths = v.Content
+	}
+	var merr error
+	extends := []*yaml.Node{}
+	for _, p := range paths {
+		if p.Kind != yaml.ScalarNode {
+			merr = multierr.Append(merr, fmt.Errorf("line %d: %s must be a path or a list of paths", p.Line, extendsKey))
+			continue
+		}
+		doc, err := l.load(resolveFrom(dir, p.Value), false, stack)
+		if err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "line %d: extending %s", p.Line, p.Value))
+			continue
+		}
+		if doc != nil {
+			extends = append(extends, doc.Content[0])
+		}
+	}
+	return extends, merr
+}
+
+// include replaces the values tagged !include by the content of their file.
+func (l *loader) include(node *yaml.Node, dir string, stack []string) error {
+	var merr error
+	for i, n := range node.Content {
+		if n.Kind != yaml.ScalarNode || n.Tag != includeTag {
+			merr = multierr.Append(merr, l.include(n, dir, stack))
+			continue
+		}
+
+		doc, err := l.load(resolveFrom(dir, n.Value), false, stack)
+		if err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "line %d: including %s", n.Line, n.Value))
+			continue
+		}
+		if doc == nil {
+			node.Content[i] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: n.Line}
+			continue
+		}
+		node.Content[i] = doc.Content[0]
+	}
+	return merr
+}
+
+// rebase rewrites the relative paths of a file to be relative to Directory.
+func (l *loader) rebase(node *yaml.Node, dir string) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if !slices.Contains(pathKeys, k.Value) {
+				continue
+			}
+			paths := []*yaml.Node{v}
+			if v.Kind == yaml.SequenceNode {
+				paths = v.Content
+			}
+			for _, p := range paths {
+				if p.Kind == yaml.ScalarNode && p.Value != "" && !filepath.IsAbs(p.Value) {
+					p.Value = l.relative(filepath.Join(dir, p.Value))
+				}
+			}
+		}
+	}
+	for _, n := range node.Content {
+		l.rebase(n, dir)
+	}
+}
+
+// relative returns the path relative to Directory if possible, else the absolute one.
+func (l *loader) relative(abs string) string {
+	rel, err := filepath.Rel(l.base, abs)
+	if err != nil {
+		return abs
+	}
+	return rel
+}
+
+// linePattern matches the lines in errors.
+var linePattern = regexp.MustCompile(`line (\d+)`)
+
+// locate rewrites the lines in the error to the source file and line.
+func (l *loader) locate(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.New(linePattern.ReplaceAllStringFunc(err.Error(), func(m string) string {
+		line, _ := strconv.Atoi(linePattern.FindStringSubmatch(m)[1])
+		for i := len(l.sources) - 1; i >= 0; i-- {
+			if src := l.sources[i]; line > src.offset {
+				return fmt.Sprintf("%s:%d", src.path, line-src.offset)
+			}
+		}
+		return m
+	}))
+}
+
+// resolveFrom returns the path relative to dir, unless absolute.
+func resolveFrom(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// shiftLines shifts the lines of the nodes by offset.
+func shiftLines(node *yaml.Node, offset int, visited map[*yaml.Node]struct{}) {
+	if _, ok := visited[node]; ok {
+		return
+	}
+	visited[node] = struct{}{}
+	node.Line += offset
+	for _, n := range node.Content {
+		shiftLines(n, offset, visited)
+	}
+}
+
+// merge merges the over node on top of the base one, and returns the result.
+// The path is the one of the nodes in the configuration, joined by dots.
+func merge(base, over *yaml.Node, path string) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && over.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(over.Content); i += 2 {
+			k, v := over.Content[i], over.Content[i+1]
+			j := mappingIndex(base, k.Value)
+			if j < 0 {
+				base.Content = append(base.Content, k, v)
+				continue
+			}
+			base.Content[j+1] = merge(base.Content[j+1], v, joinPath(path, k.Value))
+		}
+		return base
+
+	case base.Kind == yaml.SequenceNode && over.Kind == yaml.SequenceNode && mergeKeys[path] != nil:
+		keys := mergeKeys[path]
+		for _, item := range over.Content {
+			id := itemID(item, keys)
+			j := slices.IndexFunc(base.Content, func(b *yaml.Node) bool {
+				return id != "" && itemID(b, keys) == id
+			})
+			if j < 0 {
+				base.Content = append(base.Content, item)
+				continue
+			}
+			base.Content[j] = merge(base.Content[j], item, path+"[]")
+		}
+		return base
+	}
+	return over
+}
+
+// itemID returns the value of the first defined key of a list item, or an
+// empty string if none is.
+func itemID(item *yaml.Node, keys []string) string {
+	for _, key := range keys {
+		if j := mappingIndex(item, key); j >= 0 && item.Content[j+1].Value != "" {
+			return item.Content[j+1].Value
+		}
+	}
+	return ""
+}
+
+// mappingIndex returns the index of the key in a mapping node, or -1 if not found.
+func mappingIndex(node *yaml.Node, key string) int {
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// joinPath returns the path of a key in the mapping at path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// knownFields checks the mappings of the node only define fields of the type,
+// as decoding a node does not.
+func knownFields(node *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return knownFields(node.Content[0], t)
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var merr error
+	switch t.Kind() {
+	case reflect.Struct:
+		// Only check the configuration types, not e.g. time.Time
+		if t.PkgPath() != reflect.TypeOf(Config{}).PkgPath() || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if k.Tag == "!!merge" {
+				continue
+			}
+			f, ok := yamlField(t, k.Value)
+			if !ok {
+				merr = multierr.Append(merr, fmt.Errorf("line %d: field %s not found in type %s", k.Line, k.Value, t))
+				continue
+			}
+			merr = multierr.Append(merr, knownFields(v, f.Type))
+		}
+
+	case reflect.Slice, reflect.Array:
+		if node.Kind == yaml.SequenceNode {
+			for _, n := range node.Content {
+				merr = multierr.Append(merr, knownFields(n, t.Elem()))
+			}
+		}
+
+	case reflect.Map:
+		if node.Kind == yaml.MappingNode {
+			for i := 1; i < len(node.Content); i += 2 {
+				merr = multierr.Append(merr, knownFields(node.Content[i], t.Elem()))
+			}
+		}
+	}
+	return merr
+}
+
+// yamlField returns the field of the struct type with the YAML name.
+func yamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = strings.ToLower(f.Name)
+		}
+		if tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
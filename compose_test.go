@@ -0,0 +1,235 @@
+package ctfdsetup_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_U_LoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"common/base.yaml": `appearance:
+  name: Base CTF
+  description: Shared description
+legal: !include legal.yaml
+pages:
+  additional:
+  - title: Rules
+    route: rules
+    content:
+      from_file: rules.md
+  - title: Sponsors
+    route: sponsors
+    content: Our sponsors
+uploads:
+- location: logo.png
+  file:
+    from_file: logo.png
+mode: teams
+`,
+		"common/legal.yaml": `tos:
+  content:
+    from_file: tos.md
+`,
+		"common/rules.md": "Be nice.",
+		"common/tos.md":   "Terms.",
+		"common/logo.png": "png",
+		"event/index.md":  "Welcome!",
+		"event/.ctfd.yaml": `extends: ../common/base.yaml
+appearance:
+  name: Event CTF
+pages:
+  additional:
+  - title: Sponsors 2024
+    route: sponsors
+    content: Our 2024 sponsors
+  - title: Index
+    route: index
+    content:
+      from_file: event/index.md
+admin:
+  name: admin
+  email: admin@example.com
+  password: admin
+`,
+	})
+
+	// The root file paths are relative to the directory
+	t.Chdir(dir)
+	conf := ctfdsetup.NewConfig()
+	require.NoError(t, ctfdsetup.LoadConfig(filepath.Join("event", ".ctfd.yaml"), "", conf))
+
+	// Maps are merged by key
+	assert.Equal(t, "Event CTF", conf.Appearance.Name)
+	assert.Equal(t, "Shared description", conf.Appearance.Description)
+	assert.Equal(t, "teams", conf.Mode)
+	assert.Empty(t, conf.Extends)
+
+	// Pages are merged by route, files relative to the file declaring them
+	require.Len(t, conf.Pages.Additional, 3)
+	assert.Equal(t, "Rules", conf.Pages.Additional[0].Title)
+	assert.Equal(t, []byte("Be nice."), conf.Pages.Additional[0].Content.Content)
+	assert.Equal(t, "Sponsors 2024", conf.Pages.Additional[1].Title)
+	assert.Equal(t, []byte("Our 2024 sponsors"), conf.Pages.Additional[1].Content.Content)
+	assert.Equal(t, []byte("Welcome!"), conf.Pages.Additional[2].Content.Content)
+
+	// Includes are relative to the file declaring them too
+	require.Len(t, conf.Uploads, 1)
+	assert.Equal(t, []byte("png"), conf.Uploads[0].File.Content)
+	assert.Equal(t, []byte("Terms."), conf.Legal.TOS.Content.Content)
+}
+
+func Test_U_LoadConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Files    map[string]string
+		Expected string
+	}{
+		"unknown-field": {
+			Files: map[string]string{
+				"base.yaml": "appearance:\n  name: Base\n  unknown: value\n",
+				"root.yaml": "extends: base.yaml\nmode: users\n",
+			},
+			Expected: "field unknown not found in type ctfdsetup.Appearance",
+		},
+		"missing-file": {
+			Files: map[string]string{
+				"root.yaml": "mode: users\nlegal: !include missing.yaml\n",
+			},
+			Expected: "root.yaml:2: including missing.yaml",
+		},
+		"circular": {
+			Files: map[string]string{
+				"base.yaml": "extends: root.yaml\n",
+				"root.yaml": "extends: base.yaml\n",
+			},
+			Expected: "circular composition",
+		},
+		"syntax": {
+			Files: map[string]string{
+				"base.yaml": "appearance:\n  name: [\n",
+				"root.yaml": "extends: [base.yaml]\n",
+			},
+			Expected: "base.yaml:",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.Files)
+
+			err := ctfdsetup.LoadConfig(filepath.Join(dir, "root.yaml"), "", ctfdsetup.NewConfig())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.Expected)
+		})
+	}
+
+	// Errors point to the file and line they come from
+	dir := t.TempDir()
+	writeFiles(t, dir, tests["unknown-field"].Files)
+	err := ctfdsetup.LoadConfig(filepath.Join(dir, "root.yaml"), "", ctfdsetup.NewConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join(dir, "base.yaml")+":3")
+}
+
+// writeFiles writes the files content, relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+func Test_U_LoadConfigProfile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"root.yaml": `appearance:
+  name: My CTF
+security:
+  registration_code: production-code
+time:
+  start: "2024-05-25T09:00:00+02:00"
+profiles:
+  staging:
+    security:
+      registration_code: staging-code
+    settings:
+      paused: true
+      score_visibility: admins
+admin:
+  name: admin
+  email: admin@example.com
+  password: admin
+`,
+		"typo.yaml": `appearance:
+  name: My CTF
+profiles:
+  staging:
+    apperance:
+      name: Typo
+`,
+	})
+	root := filepath.Join(dir, "root.yaml")
+
+	// The profile overlays the configuration
+	conf := ctfdsetup.NewConfig()
+	require.NoError(t, ctfdsetup.LoadConfig(root, "staging", conf))
+	assert.Equal(t, "staging-code", conf.Security.RegistrationCode.Content)
+	assert.True(t, *conf.Settings.Paused)
+	assert.Equal(t, "admins", conf.Settings.ScoreVisibility)
+	assert.Equal(t, "My CTF", conf.Appearance.Name)
+	assert.Equal(t, "2024-05-25T09:00:00+02:00", *conf.Time.Start)
+	assert.Empty(t, conf.Profiles)
+
+	// No profile keeps the configuration as is
+	conf = ctfdsetup.NewConfig()
+	require.NoError(t, ctfdsetup.LoadConfig(root, "", conf))
+	assert.Equal(t, "production-code", conf.Security.RegistrationCode.Content)
+	assert.Nil(t, conf.Settings.Paused)
+
+	// Only the variables of the selected profile are required
+	writeFiles(t, dir, map[string]string{
+		"env.yaml": `security:
+  registration_code: ${PRODUCTION_CODE:?}
+profiles:
+  staging:
+    security:
+      registration_code:
+        from_env: STAGING_CODE
+    settings:
+      paused: ${STAGING_PAUSED:?}
+`,
+	})
+	env := filepath.Join(dir, "env.yaml")
+	err := ctfdsetup.LoadConfig(env, "", ctfdsetup.NewConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing environment variables: PRODUCTION_CODE (")
+	assert.NotContains(t, err.Error(), "STAGING")
+	err = ctfdsetup.LoadConfig(env, "staging", ctfdsetup.NewConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "STAGING_CODE")
+	assert.Contains(t, err.Error(), "STAGING_PAUSED")
+	assert.NotContains(t, err.Error(), "PRODUCTION_CODE")
+
+	// Undefined profiles are rejected
+	err = ctfdsetup.LoadConfig(root, "prod", ctfdsetup.NewConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "profile prod is not defined")
+
+	// Profiles are checked even if not selected
+	err = ctfdsetup.LoadConfig(filepath.Join(dir, "typo.yaml"), "", ctfdsetup.NewConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "typo.yaml:5: field apperance not found")
+}
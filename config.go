@@ -2,6 +2,7 @@ package ctfdsetup
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/invopop/jsonschema"
 	"github.com/pkg/errors"
@@ -11,13 +12,16 @@ import (
 
 type (
 	Config struct {
-		// Don't handle brackets here, should not be part of those settings but CRUD objects
-		// CustomFields are not handled as they are not predictable and would be hard to handle + bad practice (API changes on the fly)
-
 		Appearance       Appearance        `yaml:"appearance"                   json:"appearance"                   jsonschema:"required"`
 		Theme            *Theme            `yaml:"theme,omitempty"              json:"theme,omitempty"`
 		Accounts         *Accounts         `yaml:"accounts,omitempty"           json:"accounts,omitempty"`
 		Challenges       *Challenges       `yaml:"challenges,omitempty"         json:"challenges,omitempty"`
+		Brackets         *Brackets         `yaml:"brackets,omitempty"           json:"brackets,omitempty"`
+		Fields           *Fields           `yaml:"fields,omitempty"             json:"fields,omitempty"`
+		Users            *Users            `yaml:"users,omitempty"              json:"users,omitempty"`
+		Teams            *Teams            `yaml:"teams,omitempty"              json:"teams,omitempty"`
+		Staff            *Staff            `yaml:"staff,omitempty"              json:"staff,omitempty"`
+		Notifications    []*Notification   `yaml:"notifications,omitempty"      json:"notifications,omitempty"`
 		Pages            *Pages            `yaml:"pages,omitempty"              json:"pages,omitempty"`
 		MajorLeagueCyber *MajorLeagueCyber `yaml:"major_league_cyber,omitempty" json:"major_league_cyber,omitempty"`
 		Settings         *Settings         `yaml:"settings,omitempty"           json:"settings,omitempty"`
@@ -27,11 +31,24 @@ type (
 		Social           *Social           `yaml:"social,omitempty"             json:"social,omitempty"`
 		Legal            *Legal            `yaml:"legal,omitempty"              json:"legal,omitempty"`
 		Admin            Admin             `yaml:"admin"                        json:"admin"                        jsonschema:"required"`
+		Token            *Token            `yaml:"token,omitempty"              json:"token,omitempty"`
+
+		// Configuration files this one extends, merged in order before it.
+		// Relative paths resolve from this file
+		Extends []string `yaml:"extends,omitempty" json:"extends,omitempty"`
+
+		// Overlays of the configuration, one of them being selected with --profile.
+		// A profile is merged on top of the configuration, as an extended file would be
+		Profiles map[string]any `yaml:"profiles,omitempty" json:"profiles,omitempty"`
 
 		// The mode of your CTFd, either users or teams
 		Mode string `yaml:"mode,omitempty" json:"mode,omitempty" jsonschema:"enum=users,enum=teams,default=users"`
 
 		Uploads []*Upload `yaml:"uploads,omitempty" json:"uploads,omitempty"`
+
+		// What to do with the files uploaded by ctfd-setup that are removed from the uploads: keep or prune them.
+		// Files uploaded by other means (e.g. challenges files) are never pruned
+		UploadsPolicy string `yaml:"uploads_policy,omitempty" json:"uploads_policy,omitempty" jsonschema:"enum=keep,enum=prune,default=keep"`
 	}
 
 	// Appearance of the CTFd
@@ -59,7 +76,7 @@ type (
 		// The frontend theme name
 		Name string `yaml:"name,omitempty" json:"name,omitempty" jsonschema:"default=core"` // do not restrict to core-deprecated or core to avoid limiting to official themes
 
-		// The frontend theme color
+		// The frontend theme color, applied through a style prepended to the header
 		Color string `yaml:"color,omitempty" json:"color,omitempty"`
 
 		// The frontend header
@@ -124,6 +141,306 @@ type (
 
 		// Who can see and submit challenge ratings
 		ChallengeRatings string `yaml:"challenge_ratings" json:"challenge_ratings" jsonschema:"enum=public,enum=private,enum=disabled,default=public"`
+
+		// The challenges to create and update
+		List []*Challenge `yaml:"list,omitempty" json:"list,omitempty"`
+
+		// Directories to import challenges from, searched for challenge.yml files in the ctfcli format.
+		// Files a challenge refers to are relative to its directory
+		FromCtfcli []string `yaml:"from_ctfcli,omitempty" json:"from_ctfcli,omitempty"`
+
+		// Whether to delete the challenges created by ctfd-setup that are removed from the list.
+		// Challenges created by other means are never deleted
+		Prune bool `yaml:"prune,omitempty" json:"prune,omitempty"`
+	}
+
+	// Brackets to split the scoreboard in (e.g. students, professionals)
+	Brackets struct {
+		// The brackets to create and update, matched by name
+		List []*Bracket `yaml:"list,omitempty" json:"list,omitempty"`
+
+		// Whether to delete the brackets configured by ctfd-setup that are removed from the list.
+		// Brackets it never configured are never deleted
+		Prune bool `yaml:"prune,omitempty" json:"prune,omitempty"`
+	}
+
+	// Bracket of the CTFd scoreboard
+	Bracket struct {
+		// Name of the bracket, identifying it
+		Name string `yaml:"name" json:"name" jsonschema:"required"`
+
+		// Description of the bracket
+		Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+		// The bracket type, must match the CTFd mode
+		Type string `yaml:"type,omitempty" json:"type,omitempty" jsonschema:"enum=users,enum=teams"`
+	}
+
+	// Custom fields players fill on registration
+	Fields struct {
+		// The users custom fields, matched by name
+		Users []*CustomField `yaml:"users,omitempty" json:"users,omitempty"`
+
+		// The teams custom fields, matched by name
+		Teams []*CustomField `yaml:"teams,omitempty" json:"teams,omitempty"`
+
+		// Whether to delete the fields configured by ctfd-setup that are removed from the lists.
+		// Fields it never configured are never deleted
+		Prune bool `yaml:"prune,omitempty" json:"prune,omitempty"`
+
+		// Whether to delete fields even if players already filled them, dropping their values
+		Force bool `yaml:"force,omitempty" json:"force,omitempty"`
+	}
+
+	// CustomField of users or teams
+	CustomField struct {
+		// Name of the field, identifying it
+		Name string `yaml:"name" json:"name" jsonschema:"required"`
+
+		// Description of the field, displayed under it
+		Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+		// The field type
+		Type string `yaml:"type,omitempty" json:"type,omitempty" jsonschema:"enum=text,enum=boolean,default=text"`
+
+		// Whether players can edit the field after registration or not
+		Editable bool `yaml:"editable,omitempty" json:"editable,omitempty"`
+
+		// Whether the field is required on registration or not
+		Required bool `yaml:"required,omitempty" json:"required,omitempty"`
+
+		// Whether the field is shown publicly or not
+		Public bool `yaml:"public,omitempty" json:"public,omitempty"`
+	}
+
+	// Users to pre-provision
+	Users struct {
+		// The users to create and update, matched by email
+		List []*User `yaml:"list,omitempty" json:"list,omitempty"`
+
+		// A CSV file to import users from, with a header row naming the columns among
+		// email, name, password, type, verified, hidden, banned, affiliation, country and bracket
+		CSV *File `yaml:"csv,omitempty" json:"csv,omitempty"`
+
+		// A JSON file to import users from, as an array of users defined as in the list
+		JSON *File `yaml:"json,omitempty" json:"json,omitempty"`
+
+		// The local file the generated passwords are written to, for distribution
+		PasswordsFile string `yaml:"passwords_file,omitempty" json:"passwords_file,omitempty" jsonschema:"default=users-passwords.csv"`
+	}
+
+	// User to create on the CTFd
+	User struct {
+		// Name of the user, displayed as is
+		Name string `yaml:"name" json:"name" jsonschema:"required"`
+
+		// Email address of the user, identifying it
+		Email string `yaml:"email" json:"email" jsonschema:"required"`
+
+		// The user password, generated on creation if not set
+		Password *FromEnv `yaml:"password,omitempty" json:"password,omitempty"`
+
+		// The user type
+		Type string `yaml:"type,omitempty" json:"type,omitempty" jsonschema:"enum=user,enum=admin,default=user"`
+
+		// Whether the user email is verified or not
+		Verified bool `yaml:"verified,omitempty" json:"verified,omitempty"`
+
+		// Whether the user is hidden from the scoreboard or not
+		Hidden bool `yaml:"hidden,omitempty" json:"hidden,omitempty"`
+
+		// Whether the user is banned or not
+		Banned bool `yaml:"banned,omitempty" json:"banned,omitempty"`
+
+		// The user affiliation (e.g. a school, a company)
+		Affiliation *string `yaml:"affiliation,omitempty" json:"affiliation,omitempty"`
+
+		// The user country, as an ISO 3166-1 alpha-2 code
+		Country *string `yaml:"country,omitempty" json:"country,omitempty"`
+
+		// Name of the bracket the user belongs to, only in users mode
+		Bracket *string `yaml:"bracket,omitempty" json:"bracket,omitempty"`
+	}
+
+	// Teams to pre-provision, only in teams mode
+	Teams struct {
+		// The teams to create and update, matched by name
+		List []*Team `yaml:"list,omitempty" json:"list,omitempty"`
+
+		// A CSV file to import teams from, with a header row naming the columns among
+		// name, email, password, affiliation, country, bracket, members (separated by ";") and captain
+		CSV *File `yaml:"csv,omitempty" json:"csv,omitempty"`
+
+		// The local file the generated passwords are written to, for distribution
+		PasswordsFile string `yaml:"passwords_file,omitempty" json:"passwords_file,omitempty" jsonschema:"default=teams-passwords.csv"`
+	}
+
+	// Team to create on the CTFd
+	Team struct {
+		// Name of the team, identifying it
+		Name string `yaml:"name" json:"name" jsonschema:"required"`
+
+		// The team password, generated on creation if not set
+		Password *FromEnv `yaml:"password,omitempty" json:"password,omitempty"`
+
+		// The team email address
+		Email *string `yaml:"email,omitempty" json:"email,omitempty"`
+
+		// The team affiliation (e.g. a school, a company)
+		Affiliation *string `yaml:"affiliation,omitempty" json:"affiliation,omitempty"`
+
+		// The team country, as an ISO 3166-1 alpha-2 code
+		Country *string `yaml:"country,omitempty" json:"country,omitempty"`
+
+		// Name of the bracket the team belongs to
+		Bracket *string `yaml:"bracket,omitempty" json:"bracket,omitempty"`
+
+		// Names of the users to add to the team. They must already exist
+		Members []string `yaml:"members,omitempty" json:"members,omitempty"`
+
+		// Name of the team captain, among the members
+		Captain *string `yaml:"captain,omitempty" json:"captain,omitempty"`
+	}
+
+	// Staff accounts, giving every organizer their own login
+	Staff struct {
+		// The staff members to create and update as hidden administrators, matched by email
+		List []*StaffMember `yaml:"list,omitempty" json:"list,omitempty"`
+
+		// Whether to delete the staff accounts configured by ctfd-setup that are removed from the list.
+		// Accounts it never configured are never deleted
+		Prune bool `yaml:"prune,omitempty" json:"prune,omitempty"`
+	}
+
+	// StaffMember is an organizer account
+	StaffMember struct {
+		// Name of the staff member, used to log in
+		Name string `yaml:"name" json:"name" jsonschema:"required"`
+
+		// Email address of the staff member, identifying it
+		Email string `yaml:"email" json:"email" jsonschema:"required"`
+
+		// The staff member password, recommended to use the varenvs. Only set on creation,
+		// then the staff member can change it
+		Password FromEnv `yaml:"password" json:"password" jsonschema:"required"`
+	}
+
+	// Notification to send to players
+	Notification struct {
+		// The stable key identifying the notification across runs, defaults to its title
+		Key string `yaml:"key,omitempty" json:"key,omitempty"`
+
+		// Title of the notification
+		Title string `yaml:"title" json:"title" jsonschema:"required"`
+
+		// Content of the notification
+		Content *File `yaml:"content" json:"content" jsonschema:"required"`
+
+		// How the notification is displayed
+		Type string `yaml:"type,omitempty" json:"type,omitempty" jsonschema:"enum=toast,enum=alert,enum=background,default=toast"`
+
+		// Whether to play a sound on reception or not
+		Sound bool `yaml:"sound,omitempty" json:"sound,omitempty"`
+
+		// The RFC 3339 time from which the notification can be sent, when ctfd-setup runs repeatedly
+		At *time.Time `yaml:"at,omitempty" json:"at,omitempty"`
+	}
+
+	// Challenge to configure on the CTFd
+	Challenge struct {
+		// The stable key identifying the challenge across runs, defaults to its name.
+		// Set it to be able to rename the challenge without re-creating it
+		Key string `yaml:"key,omitempty" json:"key,omitempty"`
+
+		// Name of the challenge, displayed as is
+		Name string `yaml:"name" json:"name" jsonschema:"required"`
+
+		// Category of the challenge
+		Category string `yaml:"category" json:"category" jsonschema:"required"`
+
+		// The challenge description
+		Description *File `yaml:"description,omitempty" json:"description,omitempty"`
+
+		// Information to connect to the challenge (e.g. a URL, a netcat command)
+		ConnectionInfo *string `yaml:"connection_info,omitempty" json:"connection_info,omitempty"`
+
+		// The value of the challenge. For dynamic challenges, this is the initial value
+		Value int `yaml:"value" json:"value" jsonschema:"required"`
+
+		// The challenge type
+		Type string `yaml:"type,omitempty" json:"type,omitempty" jsonschema:"enum=standard,enum=dynamic,default=standard"`
+
+		// The dynamic scoring parameters, required for dynamic challenges
+		Dynamic *DynamicScoring `yaml:"dynamic,omitempty" json:"dynamic,omitempty"`
+
+		// The challenge state
+		State string `yaml:"state,omitempty" json:"state,omitempty" jsonschema:"enum=visible,enum=hidden,default=visible"`
+
+		// The maximum number of attempts, unlimited if not set
+		MaxAttempts *int `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+
+		// The flags to solve the challenge
+		Flags []*Flag `yaml:"flags,omitempty" json:"flags,omitempty"`
+
+		// The hints to help players
+		Hints []*Hint `yaml:"hints,omitempty" json:"hints,omitempty"`
+
+		// The tags of the challenge
+		Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+
+		// The topics of the challenge, only visible to administrators
+		Topics []string `yaml:"topics,omitempty" json:"topics,omitempty"`
+
+		// The files attached to the challenge
+		Files []*File `yaml:"files,omitempty" json:"files,omitempty"`
+
+		// The challenges to solve before accessing this one
+		Requirements *Requirements `yaml:"requirements,omitempty" json:"requirements,omitempty"`
+	}
+
+	// Requirements to access a challenge
+	Requirements struct {
+		// Keys of the challenges to solve first. Challenges not managed by ctfd-setup are referred to by name
+		Prerequisites []string `yaml:"prerequisites" json:"prerequisites" jsonschema:"required"`
+
+		// Whether to show the challenge anonymized until unlocked, rather than hiding it
+		Anonymize bool `yaml:"anonymize,omitempty" json:"anonymize,omitempty"`
+	}
+
+	// DynamicScoring defines how the value of a dynamic challenge decreases with solves
+	DynamicScoring struct {
+		// The value once the minimum is reached
+		Minimum int `yaml:"minimum" json:"minimum" jsonschema:"required"`
+
+		// The number of solves before the minimum is reached (linear), or the decay factor (logarithmic)
+		Decay int `yaml:"decay" json:"decay" jsonschema:"required"`
+
+		// The decay function
+		Function string `yaml:"function,omitempty" json:"function,omitempty" jsonschema:"enum=linear,enum=logarithmic,default=logarithmic"`
+	}
+
+	// Flag of a challenge
+	Flag struct {
+		// The flag content, recommended to use the varenvs
+		Content FromEnv `yaml:"content" json:"content" jsonschema:"required"`
+
+		// The flag type
+		Type string `yaml:"type,omitempty" json:"type,omitempty" jsonschema:"enum=static,enum=regex,default=static"`
+
+		// Whether the flag is case insensitive or not
+		CaseInsensitive bool `yaml:"case_insensitive,omitempty" json:"case_insensitive,omitempty"`
+	}
+
+	// Hint of a challenge
+	Hint struct {
+		// The hint title
+		Title *string `yaml:"title,omitempty" json:"title,omitempty"`
+
+		// The hint content
+		Content string `yaml:"content" json:"content" jsonschema:"required"`
+
+		// The cost to unlock the hint
+		Cost int `yaml:"cost,omitempty" json:"cost,omitempty"`
 	}
 
 	// Pages global configuration
@@ -132,6 +449,10 @@ type (
 		RobotsTxt *File `yaml:"robots_txt,omitempty" json:"robots_txt,omitempty"`
 
 		Additional []Page `yaml:"additional,omitempty" json:"additional,omitempty"`
+
+		// Which pages to delete when not configured: the ones created by ctfd-setup (managed), all of them, or none.
+		// Unless set to all, pages that were not created by ctfd-setup are never overwritten
+		Prune string `yaml:"prune,omitempty" json:"prune,omitempty" jsonschema:"enum=managed,enum=all,enum=none,default=managed"`
 	}
 
 	// Page to configure and display on the CTFd
@@ -163,8 +484,8 @@ type (
 		// The MajorLeagueCyber OAuth ClientID
 		ClientID *string `yaml:"client_id,omitempty" json:"client_id,omitempty"`
 
-		// The MajorLeagueCyber OAuth Client Secret
-		ClientSecret *string `yaml:"client_secret,omitempty" json:"client_secret,omitempty"`
+		// The MajorLeagueCyber OAuth Client Secret, recommended to use the varenvs
+		ClientSecret *FromEnv `yaml:"client_secret,omitempty" json:"client_secret,omitempty"`
 	}
 
 	// Settings for resources visibility
@@ -191,7 +512,7 @@ type (
 		HTMLSanitization *bool `yaml:"html_sanitization,omitempty" json:"html_sanitization,omitempty"`
 
 		// The registration code (secret) to join the CTF
-		RegistrationCode *string `yaml:"registration_code,omitempty" json:"registration_code,omitempty"`
+		RegistrationCode *FromEnv `yaml:"registration_code,omitempty" json:"registration_code,omitempty"`
 	}
 
 	// Email rules and server credentials
@@ -223,8 +544,8 @@ type (
 		// The username to log in to the mail server
 		Username *string `yaml:"username,omitempty" json:"username,omitempty"`
 
-		// The password to log in to the mail server
-		Password *string `yaml:"password,omitempty" json:"password,omitempty"`
+		// The password to log in to the mail server, recommended to use the varenvs
+		Password *FromEnv `yaml:"password,omitempty" json:"password,omitempty"`
 
 		// Whether to turn on TLS/SSL or not
 		TLS_SSL *bool `yaml:"tls_ssl,omitempty" json:"tls_ssl,omitempty"`
@@ -287,11 +608,44 @@ type (
 		// The administrator name. Immutable, or need the administrator to change the CTFd data AND the configuration file
 		Name FromEnv `yaml:"name" json:"name" jsonschema:"required"`
 
-		// The administrator email address. Immutable, or need the administrator to change the CTFd data AND the configuration file
+		// The administrator email address. To change it, rotate it
 		Email FromEnv `yaml:"email" json:"email" jsonschema:"required"`
 
-		// The administrator password, recommended to use the varenvs. Immutable, or need the administrator to change the CTFd data AND the configuration file
+		// The administrator password, recommended to use the varenvs. To change it, rotate it
 		Password FromEnv `yaml:"password" json:"password" jsonschema:"required"`
+
+		// The credentials to rotate the administrator ones to, e.g. after a leak
+		Rotate *AdminRotation `yaml:"rotate,omitempty" json:"rotate,omitempty"`
+	}
+
+	// AdminRotation defines the new administrator credentials.
+	//
+	// Once applied and checked by logging in with them, they can replace the
+	// administrator ones in the configuration. Until then, they are used to log in
+	// if the administrator ones got rejected.
+	AdminRotation struct {
+		// The new administrator email address
+		Email *FromEnv `yaml:"email,omitempty" json:"email,omitempty"`
+
+		// The new administrator password, recommended to use the varenvs
+		Password *FromEnv `yaml:"password,omitempty" json:"password,omitempty"`
+	}
+
+	// Token bootstraps an API token for automation once the CTFd instance is set up,
+	// such that later runs do not need the administrator credentials.
+	Token struct {
+		// Where to write the token: to a file (which later runs reuse), to the standard output,
+		// or to a GitHub Actions step output named "api_key". Does not bootstrap one if let empty
+		Output string `yaml:"output,omitempty" json:"output,omitempty" jsonschema:"enum=file,enum=stdout,enum=github"`
+
+		// The file to write the token to then read it from, if the output is a file
+		File string `yaml:"file,omitempty" json:"file,omitempty" jsonschema:"default=.ctfd-token"`
+
+		// The token expiration date (e.g. 2025-01-01). CTFd defaults it to 30 days if let empty
+		Expiration string `yaml:"expiration,omitempty" json:"expiration,omitempty" jsonschema:"format=date"`
+
+		// The token description, displayed in the administrator settings
+		Description string `yaml:"description,omitempty" json:"description,omitempty" jsonschema:"default=ctfd-setup automation"`
 	}
 
 	// Upload defines a file or content to upload as per the setup
@@ -299,7 +653,7 @@ type (
 	// Does not upload twice if already exist.
 	// One use case is to upload logos and use them in an alternative index.html page for an event.
 	//
-	// WARNING: if a file is removed from the list, it won't be deleted by ctfd-setup
+	// If a file is removed from the list, it is deleted only if the uploads policy is to prune them.
 	Upload struct {
 		File *File `yaml:"file" json:"file" jsonschema:"required"`
 
@@ -324,10 +678,24 @@ func NewConfig() *Config {
 			MaxAttemptsBehavior: "lockout",
 			ChallengeRatings:    "public",
 		},
+		Brackets: &Brackets{},
+		Fields:   &Fields{},
+		Users: &Users{
+			PasswordsFile: "users-passwords.csv", // default value
+		},
+		Teams: &Teams{
+			PasswordsFile: "teams-passwords.csv", // default value
+		},
+		Staff: &Staff{},
 		Pages: &Pages{
 			RobotsTxt: &File{},
+			Prune:     PagesPruneManaged, // default value
 		},
 		MajorLeagueCyber: &MajorLeagueCyber{},
+		Token: &Token{
+			File:        TokenFile,               // default value
+			Description: "ctfd-setup automation", // default value
+		},
 		Settings: &Settings{
 			ChallengeVisibility:    "private", // default value
 			AccountVisibility:      "public",  // default value
@@ -348,8 +716,12 @@ func NewConfig() *Config {
 				Content: &File{},
 			},
 		},
-		Mode:    "users", // default value
-		Uploads: []*Upload{},
+		Admin: Admin{
+			Rotate: &AdminRotation{},
+		},
+		Mode:          "users", // default value
+		Uploads:       []*Upload{},
+		UploadsPolicy: UploadsKeep, // default value
 	}
 }
 
@@ -359,6 +731,7 @@ func (conf Config) Schema() ([]byte, error) {
 	_ = reflector.AddGoComments("github.com/ctfer-io/ctfd-setup", "./") // this could fail once binary is compiled, thus ignored (no problem)
 	r := reflector.Reflect(&Config{})
 	r.ID = "https://json.schemastore.org/ctfd.json" // set the Schemastore ID
+	allowInterpolation(r)
 
 	return json.MarshalIndent(r, "", "  ")
 }
@@ -385,5 +758,13 @@ func (conf Config) Validate() error {
 		}
 		return merr
 	}
-	return nil
+	return multierr.Combine(
+		validateBrackets(conf.Brackets, conf.Mode),
+		validateFields(conf.Fields),
+		validateUsers(conf.Users, conf.Mode, conf.Admin),
+		validateTeams(conf.Teams, conf.Mode),
+		validateStaff(conf.Staff, conf.Admin, conf.Users),
+		validateNotifications(conf.Notifications),
+		validateChallenges(conf.Challenges),
+	)
 }
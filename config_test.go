@@ -1,10 +1,14 @@
 package ctfdsetup_test
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 
 	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/ctfer-io/go-ctfd/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_U_ConfigSchema(t *testing.T) {
@@ -17,3 +21,513 @@ func Test_U_ConfigSchema(t *testing.T) {
 	assert.NoError(err)
 	assert.NotEmpty(schema)
 }
+
+func Test_U_ValidateBrackets(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Mode      string
+		Brackets  []*ctfdsetup.Bracket
+		ExpectErr bool
+	}{
+		"default-type": {
+			Mode: "teams",
+			Brackets: []*ctfdsetup.Bracket{
+				{Name: "Students"},
+			},
+			ExpectErr: false,
+		},
+		"matching-type": {
+			Mode: "users",
+			Brackets: []*ctfdsetup.Bracket{
+				{Name: "Students", Type: "users"},
+			},
+			ExpectErr: false,
+		},
+		"mismatching-type": {
+			Mode: "users",
+			Brackets: []*ctfdsetup.Bracket{
+				{Name: "Students", Type: "teams"},
+			},
+			ExpectErr: true,
+		},
+		"duplicated-name": {
+			Mode: "users",
+			Brackets: []*ctfdsetup.Bracket{
+				{Name: "Students"},
+				{Name: "Students"},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			conf := ctfdsetup.NewConfig()
+			conf.Mode = tt.Mode
+			conf.Brackets.List = tt.Brackets
+
+			err := conf.Validate()
+			if tt.ExpectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_U_ValidateTeams(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Mode      string
+		CSV       string
+		ExpectErr bool
+	}{
+		"valid": {
+			Mode:      "teams",
+			CSV:       "name,email,members,captain\nCTFer,team@ctfer.io,alice;bob,alice\n",
+			ExpectErr: false,
+		},
+		"users-mode": {
+			Mode:      "users",
+			CSV:       "name\nCTFer\n",
+			ExpectErr: true,
+		},
+		"unknown-column": {
+			Mode:      "teams",
+			CSV:       "name,website\nCTFer,https://ctfer.io\n",
+			ExpectErr: true,
+		},
+		"missing-name": {
+			Mode:      "teams",
+			CSV:       "name,email\n,team@ctfer.io\n",
+			ExpectErr: true,
+		},
+		"captain-not-member": {
+			Mode:      "teams",
+			CSV:       "name,members,captain\nCTFer,alice,bob\n",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			conf := ctfdsetup.NewConfig()
+			conf.Mode = tt.Mode
+			conf.Teams.CSV = &ctfdsetup.File{Content: []byte(tt.CSV)}
+
+			err := conf.Validate()
+			if tt.ExpectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_U_ValidateUsers(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Mode      string
+		CSV       string
+		JSON      string
+		ExpectErr bool
+	}{
+		"csv": {
+			Mode:      "users",
+			CSV:       "email,name,verified,bracket\nalice@ctfer.io,alice,true,Students\n",
+			ExpectErr: false,
+		},
+		"json": {
+			Mode:      "users",
+			JSON:      `[{"email": "alice@ctfer.io", "name": "alice", "password": "s3cr3t", "type": "admin"}]`,
+			ExpectErr: false,
+		},
+		"duplicated-email": {
+			Mode:      "users",
+			CSV:       "email,name\nalice@ctfer.io,alice\n",
+			JSON:      `[{"email": "Alice@ctfer.io", "name": "alice"}]`,
+			ExpectErr: true,
+		},
+		"invalid-bool": {
+			Mode:      "users",
+			CSV:       "email,name,hidden\nalice@ctfer.io,alice,maybe\n",
+			ExpectErr: true,
+		},
+		"unknown-json-field": {
+			Mode:      "users",
+			JSON:      `[{"email": "alice@ctfer.io", "name": "alice", "team": "CTFer"}]`,
+			ExpectErr: true,
+		},
+		"bracket-in-teams-mode": {
+			Mode:      "teams",
+			CSV:       "email,name,bracket\nalice@ctfer.io,alice,Students\n",
+			ExpectErr: true,
+		},
+		"administrator": {
+			Mode:      "users",
+			CSV:       "email,name\nCTFer-io@protonmail.com,ctfer\n",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			conf := ctfdsetup.NewConfig()
+			conf.Mode = tt.Mode
+			conf.Admin.Email.Content = "ctfer-io@protonmail.com"
+			conf.Users.CSV = &ctfdsetup.File{Content: []byte(tt.CSV)}
+			conf.Users.JSON = &ctfdsetup.File{Content: []byte(tt.JSON)}
+
+			err := conf.Validate()
+			if tt.ExpectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_U_ValidateStaff(t *testing.T) {
+	t.Parallel()
+
+	alice := func() *ctfdsetup.StaffMember {
+		return &ctfdsetup.StaffMember{
+			Name:     "alice",
+			Email:    "alice@ctfer.io",
+			Password: ctfdsetup.FromEnv{Content: "secret"},
+		}
+	}
+
+	var tests = map[string]struct {
+		List      []*ctfdsetup.StaffMember
+		ExpectErr bool
+	}{
+		"valid": {
+			List:      []*ctfdsetup.StaffMember{alice()},
+			ExpectErr: false,
+		},
+		"duplicated-email": {
+			List: []*ctfdsetup.StaffMember{alice(), {
+				Name:     "bob",
+				Email:    "ALICE@ctfer.io",
+				Password: ctfdsetup.FromEnv{Content: "secret"},
+			}},
+			ExpectErr: true,
+		},
+		"missing-password": {
+			List: []*ctfdsetup.StaffMember{{
+				Name:  "bob",
+				Email: "bob@ctfer.io",
+			}},
+			ExpectErr: true,
+		},
+		"administrator": {
+			List: []*ctfdsetup.StaffMember{{
+				Name:     "ctfer",
+				Email:    "ctfer-io@protonmail.com",
+				Password: ctfdsetup.FromEnv{Content: "secret"},
+			}},
+			ExpectErr: true,
+		},
+		"user": {
+			List: []*ctfdsetup.StaffMember{{
+				Name:     "bob",
+				Email:    "Bob@ctfer.io",
+				Password: ctfdsetup.FromEnv{Content: "secret"},
+			}},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			conf := ctfdsetup.NewConfig()
+			conf.Users.List = []*ctfdsetup.User{{Name: "bob", Email: "bob@ctfer.io"}}
+			conf.Admin.Name.Content = "ctfer"
+			conf.Admin.Email.Content = "ctfer-io@protonmail.com"
+			conf.Staff.List = tt.List
+
+			err := conf.Validate()
+			if tt.ExpectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_U_ValidateChallenges(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Type      string
+		Dynamic   *ctfdsetup.DynamicScoring
+		ExpectErr bool
+	}{
+		"standard": {
+			Type:      "standard",
+			ExpectErr: false,
+		},
+		"dynamic": {
+			Type:      "dynamic",
+			Dynamic:   &ctfdsetup.DynamicScoring{Minimum: 50, Decay: 20},
+			ExpectErr: false,
+		},
+		"dynamic-without-scoring": {
+			Type:      "dynamic",
+			ExpectErr: true,
+		},
+		"standard-with-scoring": {
+			Type:      "standard",
+			Dynamic:   &ctfdsetup.DynamicScoring{Minimum: 50, Decay: 20},
+			ExpectErr: true,
+		},
+		"default-with-scoring": {
+			Dynamic:   &ctfdsetup.DynamicScoring{Minimum: 50, Decay: 20},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			conf := ctfdsetup.NewConfig()
+			conf.Challenges.List = []*ctfdsetup.Challenge{{
+				Name:     "Warmup",
+				Category: "misc",
+				Value:    500,
+				Type:     tt.Type,
+				Dynamic:  tt.Dynamic,
+			}}
+
+			err := conf.Validate()
+			if tt.ExpectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// appliedByOtherMeans are the Config leaf fields that are not CTFd configs,
+// but applied through other API calls.
+var appliedByOtherMeans = []string{
+	"Theme.Logo",      // uploaded then set through its own endpoint
+	"Theme.SmallIcon", // uploaded then set through its own endpoint
+	"Brackets.List",
+	"Brackets.Prune",
+	"Fields",
+	"Users",
+	"Teams",
+	"Staff",
+	"Challenges.FromCtfcli",
+	"Challenges.List",
+	"Challenges.Prune",
+	"Pages.Additional",
+	"Pages.Prune",
+	"Admin.Name",     // used on bare setup and login
+	"Admin.Email",    // used on bare setup
+	"Admin.Password", // used on bare setup and login
+	"Admin.Rotate",   // patched on the administrator user
+	"Token",          // used after bare setup, then to connect
+	"Extends",        // composed when loading the configuration file
+	"Profiles",       // composed when loading the configuration file
+	"Notifications",
+	"Uploads",
+	"UploadsPolicy",
+}
+
+// configKeys are the CTFd config keys each Config leaf field is applied to.
+var configKeys = map[string]string{
+	"Appearance.Name":                         "ctf_name",
+	"Appearance.Description":                  "ctf_description",
+	"Appearance.DefaultLocale":                "default_locale",
+	"Theme.Name":                              "ctf_theme",
+	"Theme.Color":                             "theme_header", // injected as a style
+	"Theme.Header":                            "theme_header",
+	"Theme.Footer":                            "theme_footer",
+	"Theme.Settings":                          "theme_settings",
+	"Accounts.DomainWhitelist":                "domain_whitelist",
+	"Accounts.DomainBlacklist":                "domain_blacklist",
+	"Accounts.VerifyEmails":                   "verify_emails",
+	"Accounts.TeamCreation":                   "team_creation",
+	"Accounts.TeamSize":                       "team_size",
+	"Accounts.PasswordMinLength":              "password_min_length",
+	"Accounts.NumTeams":                       "num_teams",
+	"Accounts.NumUsers":                       "num_users",
+	"Accounts.TeamDisbanding":                 "team_disbanding",
+	"Accounts.IncorrectSubmissionsPerMinute":  "incorrect_submissions_per_min",
+	"Accounts.NameChanges":                    "name_changes",
+	"Challenges.ViewSelfSubmission":           "view_self_submissions",
+	"Challenges.MaxAttemptsBehavior":          "max_attempts_behavior",
+	"Challenges.MaxAttemptsTimeout":           "max_attempts_timeout",
+	"Challenges.HintsFreePublicAccess":        "hints_free_public_access",
+	"Challenges.ChallengeRatings":             "challenge_ratings",
+	"Pages.RobotsTxt":                         "robots_txt",
+	"MajorLeagueCyber.ClientID":               "oauth_client_id",
+	"MajorLeagueCyber.ClientSecret":           "oauth_client_secret",
+	"Settings.ChallengeVisibility":            "challenge_visibility",
+	"Settings.AccountVisibility":              "account_visibility",
+	"Settings.ScoreVisibility":                "score_visibility",
+	"Settings.RegistrationVisibility":         "registration_visibility",
+	"Settings.Paused":                         "paused",
+	"Security.HTMLSanitization":               "html_sanitization",
+	"Security.RegistrationCode":               "registration_code",
+	"Email.Registration.Subject":              "successful_registration_email_subject",
+	"Email.Registration.Body":                 "successful_registration_email_body",
+	"Email.Confirmation.Subject":              "verification_email_subject",
+	"Email.Confirmation.Body":                 "verification_email_body",
+	"Email.NewAccount.Subject":                "user_creation_email_subject",
+	"Email.NewAccount.Body":                   "user_creation_email_body",
+	"Email.PasswordReset.Subject":             "password_change_alert_subject",
+	"Email.PasswordReset.Body":                "password_change_alert_body",
+	"Email.PasswordResetConfirmation.Subject": "password_reset_subject",
+	"Email.PasswordResetConfirmation.Body":    "password_reset_body",
+	"Email.From":                              "mailfrom_addr",
+	"Email.Server":                            "mail_server",
+	"Email.Port":                              "mail_port",
+	"Email.Username":                          "mail_username",
+	"Email.Password":                          "mail_password",
+	"Email.TLS_SSL":                           "mail_ssl",
+	"Email.STARTTLS":                          "mail_tls",
+	"Time.Start":                              "start",
+	"Time.End":                                "end",
+	"Time.Freeze":                             "freeze",
+	"Time.ViewAfter":                          "view_after_ctf",
+	"Social.Shares":                           "social_shares",
+	"Social.Template":                         "social_share_solve_template",
+	"Legal.TOS.URL":                           "tos_url",
+	"Legal.TOS.Content":                       "tos_text",
+	"Legal.PrivacyPolicy.URL":                 "privacy_url",
+	"Legal.PrivacyPolicy.Content":             "privacy_text",
+	"Mode":                                    "user_mode",
+}
+
+func Test_U_ExportDefaults(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Missing settings are exported as CTFd's defaults
+	conf := ctfdsetup.ExportConfig(&api.PatchConfigsParams{})
+	assert.Equal("private", conf.Settings.ChallengeVisibility)
+	assert.Equal("public", conf.Settings.AccountVisibility)
+	assert.Equal("public", conf.Settings.ScoreVisibility)
+	assert.Equal("public", conf.Settings.RegistrationVisibility)
+}
+
+func Test_U_ConfigCoverage(t *testing.T) {
+	t.Parallel()
+
+	// Mail authentication is applied only when both are defined
+	base := func() *ctfdsetup.Config {
+		conf := ctfdsetup.NewConfig()
+		conf.Email.Username = new(string)
+		conf.Email.Password = &ctfdsetup.FromEnv{}
+		return conf
+	}
+	baseline, err := ctfdsetup.ConfigValues(base())
+	require.NoError(t, err)
+
+	for _, path := range leaves(reflect.TypeOf(ctfdsetup.Config{}), "") {
+		if isAppliedByOtherMeans(path) {
+			continue
+		}
+
+		t.Run(path, func(t *testing.T) {
+			conf := base()
+			setLeaf(t, reflect.ValueOf(conf).Elem(), strings.Split(path, "."))
+
+			key, ok := configKeys[path]
+			require.True(t, ok, "field %s has no expected CTFd config key", path)
+
+			values, err := ctfdsetup.ConfigValues(conf)
+			require.NoError(t, err)
+			assert.NotEqual(t, baseline[key], values[key], "field %s is not mapped to the CTFd config %s", path, key)
+			for k, v := range values {
+				if k != key {
+					assert.Equal(t, baseline[k], v, "field %s changes the CTFd config %s", path, k)
+				}
+			}
+		})
+	}
+}
+
+func isAppliedByOtherMeans(path string) bool {
+	for _, p := range appliedByOtherMeans {
+		if path == p || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// leaves returns the path of all the leaf fields of a struct type.
+// Files and values from environment are considered leaves.
+func leaves(t reflect.Type, prefix string) []string {
+	out := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		path := prefix + f.Name
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != reflect.TypeOf(ctfdsetup.File{}) && ft != reflect.TypeOf(ctfdsetup.FromEnv{}) {
+			out = append(out, leaves(ft, path+".")...)
+			continue
+		}
+		out = append(out, path)
+	}
+	return out
+}
+
+// setLeaf sets the field at path to a value different from the current one.
+func setLeaf(t *testing.T, v reflect.Value, path []string) {
+	f := v.FieldByName(path[0])
+	if f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		f = f.Elem()
+	}
+	if len(path) > 1 {
+		setLeaf(t, f, path[1:])
+		return
+	}
+
+	switch f.Interface().(type) {
+	case string:
+		f.SetString("coverage")
+	case bool:
+		f.SetBool(!f.Bool())
+	case int:
+		f.SetInt(f.Int() + 42)
+	case ctfdsetup.File:
+		f.Set(reflect.ValueOf(ctfdsetup.File{Content: []byte("coverage")}))
+	case ctfdsetup.FromEnv:
+		f.Set(reflect.ValueOf(ctfdsetup.FromEnv{Content: "coverage"}))
+	default:
+		t.Fatalf("unsupported leaf type %s", f.Type())
+	}
+}
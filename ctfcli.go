@@ -0,0 +1,194 @@
+package ctfdsetup
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ctfcliChallenge is a challenge.yml file in the ctfcli format.
+// Deployment attributes (e.g. image, host, healthcheck) are ignored.
+type ctfcliChallenge struct {
+	Name           string              `yaml:"name"`
+	Category       string              `yaml:"category"`
+	Description    string              `yaml:"description"`
+	ConnectionInfo *string             `yaml:"connection_info"`
+	Value          int                 `yaml:"value"`
+	Type           string              `yaml:"type"`
+	Extra          *ctfcliExtra        `yaml:"extra"`
+	State          string              `yaml:"state"`
+	Attempts       *int                `yaml:"attempts"`
+	Flags          []ctfcliFlag        `yaml:"flags"`
+	Hints          []ctfcliHint        `yaml:"hints"`
+	Tags           []ctfcliTag         `yaml:"tags"`
+	Topics         []string            `yaml:"topics"`
+	Files          []string            `yaml:"files"`
+	Requirements   *ctfcliRequirements `yaml:"requirements"`
+}
+
+// ctfcliExtra are the dynamic scoring parameters.
+type ctfcliExtra struct {
+	Initial  int    `yaml:"initial"`
+	Decay    int    `yaml:"decay"`
+	Minimum  int    `yaml:"minimum"`
+	Function string `yaml:"function"`
+}
+
+// ctfcliFlag is either the content of a static flag, or a flag definition.
+type ctfcliFlag struct {
+	Type    string `yaml:"type"`
+	Content string `yaml:"content"`
+	Data    string `yaml:"data"`
+}
+
+func (f *ctfcliFlag) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		f.Content = node.Value
+		return nil
+	}
+	type lf ctfcliFlag
+	return node.Decode((*lf)(f))
+}
+
+// ctfcliHint is either the content of a free hint, or a hint definition.
+type ctfcliHint struct {
+	Title   *string `yaml:"title"`
+	Content string  `yaml:"content"`
+	Cost    int     `yaml:"cost"`
+}
+
+func (h *ctfcliHint) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		h.Content = node.Value
+		return nil
+	}
+	type lh ctfcliHint
+	return node.Decode((*lh)(h))
+}
+
+// ctfcliTag is either the tag value, or a tag definition.
+type ctfcliTag struct {
+	Value string `yaml:"value"`
+}
+
+func (t *ctfcliTag) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Value = node.Value
+		return nil
+	}
+	type lt ctfcliTag
+	return node.Decode((*lt)(t))
+}
+
+// ctfcliRequirements is either the list of prerequisites names, or a requirements
+// definition.
+type ctfcliRequirements struct {
+	Prerequisites []string `yaml:"prerequisites"`
+	Anonymize     bool     `yaml:"anonymize"`
+}
+
+func (r *ctfcliRequirements) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&r.Prerequisites)
+	}
+	type lr ctfcliRequirements
+	return node.Decode((*lr)(r))
+}
+
+// challengesFromCtfcli imports the challenges of the challenge.yml files found
+// under the directory, relative to Directory unless absolute.
+func challengesFromCtfcli(dir string) ([]*Challenge, error) {
+	challs := []*Challenge{}
+	err := filepath.WalkDir(resolvePath(dir), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (d.Name() != "challenge.yml" && d.Name() != "challenge.yaml") {
+			return nil
+		}
+		ch, err := challengeFromCtfcli(p)
+		if err != nil {
+			return errors.Wrapf(err, "importing %s", p)
+		}
+		challs = append(challs, ch)
+		return nil
+	})
+	return challs, err
+}
+
+// challengeFromCtfcli reads a challenge.yml file, and its files relative to its directory.
+func challengeFromCtfcli(p string) (*Challenge, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	cc := &ctfcliChallenge{}
+	if err := yaml.NewDecoder(bytes.NewReader(b)).Decode(cc); err != nil {
+		return nil, err
+	}
+
+	ch := &Challenge{
+		Name:           cc.Name,
+		Category:       cc.Category,
+		Description:    &File{Content: []byte(cc.Description)},
+		ConnectionInfo: cc.ConnectionInfo,
+		Value:          cc.Value,
+		Type:           cc.Type,
+		State:          cc.State,
+		MaxAttempts:    cc.Attempts,
+		Topics:         cc.Topics,
+	}
+	if cc.Type == "dynamic" {
+		if cc.Extra == nil {
+			return nil, fmt.Errorf("challenge %s: dynamic challenges require the extra scoring parameters", cc.Name)
+		}
+		ch.Value = cc.Extra.Initial
+		ch.Dynamic = &DynamicScoring{
+			Minimum:  cc.Extra.Minimum,
+			Decay:    cc.Extra.Decay,
+			Function: cc.Extra.Function,
+		}
+	}
+	for _, f := range cc.Flags {
+		ch.Flags = append(ch.Flags, &Flag{
+			Content:         FromEnv{Content: f.Content},
+			Type:            f.Type,
+			CaseInsensitive: f.Data == flagCaseInsensitive,
+		})
+	}
+	for _, h := range cc.Hints {
+		ch.Hints = append(ch.Hints, &Hint{
+			Title:   h.Title,
+			Content: h.Content,
+			Cost:    h.Cost,
+		})
+	}
+	for _, t := range cc.Tags {
+		ch.Tags = append(ch.Tags, t.Value)
+	}
+	if cc.Requirements != nil {
+		ch.Requirements = &Requirements{
+			Prerequisites: cc.Requirements.Prerequisites,
+			Anonymize:     cc.Requirements.Anonymize,
+		}
+	}
+
+	// Files are relative to the challenge directory
+	dir := filepath.Dir(p)
+	for _, name := range cc.Files {
+		fc, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		ch.Files = append(ch.Files, &File{
+			Name:    filepath.Join(dir, name),
+			Content: fc,
+		})
+	}
+	return ch, nil
+}
@@ -0,0 +1,88 @@
+package ctfdsetup_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_U_Ctfcli(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	chall := filepath.Join(dir, "web", "login")
+	require.NoError(t, os.MkdirAll(chall, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(chall, "challenge.yml"), []byte(`name: Login
+author: someone
+category: web
+description: Log in as admin.
+type: dynamic
+extra:
+  initial: 500
+  decay: 20
+  minimum: 50
+image: .
+host: null
+flags:
+- CTF{static}
+- type: regex
+  content: CTF\{.*\}
+- type: static
+  content: ctf{insensitive}
+  data: case_insensitive
+hints:
+- Try harder.
+- content: Look at the cookies.
+  cost: 10
+tags:
+- easy
+- value: web
+files:
+- dist/login.zip
+requirements:
+- Warmup
+`), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(chall, "dist"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(chall, "dist", "login.zip"), []byte("zip"), 0644))
+
+	challs := &ctfdsetup.Challenges{
+		FromCtfcli: []string{dir},
+		List: []*ctfdsetup.Challenge{
+			{Name: "Warmup", Category: "misc", Value: 100},
+		},
+	}
+	list, err := challs.All()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	ch := list[1]
+	assert.Equal(t, "Login", ch.Name)
+	assert.Equal(t, "web", ch.Category)
+	assert.Equal(t, "Log in as admin.", string(ch.Description.Content))
+	assert.Equal(t, 500, ch.Value)
+	require.NotNil(t, ch.Dynamic)
+	assert.Equal(t, 50, ch.Dynamic.Minimum)
+	assert.Equal(t, 20, ch.Dynamic.Decay)
+
+	require.Len(t, ch.Flags, 3)
+	assert.Equal(t, "CTF{static}", ch.Flags[0].Content.Content)
+	assert.Equal(t, "regex", ch.Flags[1].Type)
+	assert.True(t, ch.Flags[2].CaseInsensitive)
+
+	require.Len(t, ch.Hints, 2)
+	assert.Equal(t, "Try harder.", ch.Hints[0].Content)
+	assert.Equal(t, 10, ch.Hints[1].Cost)
+
+	assert.Equal(t, []string{"easy", "web"}, ch.Tags)
+
+	require.Len(t, ch.Files, 1)
+	assert.Equal(t, filepath.Join(chall, "dist", "login.zip"), ch.Files[0].Name)
+	assert.Equal(t, []byte("zip"), ch.Files[0].Content)
+
+	require.NotNil(t, ch.Requirements)
+	assert.Equal(t, []string{"Warmup"}, ch.Requirements.Prerequisites)
+}
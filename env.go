@@ -3,47 +3,179 @@ package ctfdsetup
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/invopop/jsonschema"
+	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
+// StrictSecrets rejects the secret files readable by anyone.
+var StrictSecrets bool
+
 type FromEnv struct {
 	Content string `yaml:"-" json:"-" jsonschema:"-"`
+
+	// Name of the environment variable the content comes from, if any
+	Name string `yaml:"-" json:"-" jsonschema:"-"`
+
+	// Encrypted is the age-encrypted armored content, if any
+	Encrypted string `yaml:"-" json:"-" jsonschema:"-"`
+
+	// SecretFile is the file the content comes from, if any (e.g. a Docker or Kubernetes secret)
+	SecretFile string `yaml:"-" json:"-" jsonschema:"-"`
+
+	// Default is the content if the environment variable or secret file is not defined or empty
+	Default *string `yaml:"-" json:"-" jsonschema:"-"`
+
+	// Required is whether the environment variable or secret file must be defined, defaults to true
+	Required *bool `yaml:"-" json:"-" jsonschema:"-"`
 }
 
 var _ yaml.Unmarshaler = (*FromEnv)(nil)
+var _ yaml.Marshaler = (*FromEnv)(nil)
 
 func (fe *FromEnv) UnmarshalYAML(node *yaml.Node) error {
+	if err := fe.unmarshalYAML(node); err != nil {
+		return errors.Wrapf(err, "line %d", node.Line)
+	}
+	return nil
+}
+
+func (fe *FromEnv) unmarshalYAML(node *yaml.Node) error {
 	if node.Value != "" {
+		if isEncrypted(node.Value) {
+			b, err := decrypt(node.Value)
+			if err != nil {
+				return err
+			}
+			fe.Encrypted = node.Value
+			fe.Content = string(b)
+			return nil
+		}
 		fe.Content = node.Value
 		return nil
 	}
 	type lfe struct {
-		FromEnv *string `yaml:"from_env"`
+		FromEnv        *string `yaml:"from_env"`
+		FromSecretFile *string `yaml:"from_secret_file"`
+		Default        *string `yaml:"default"`
+		Required       *bool   `yaml:"required"`
 	}
 	var lfev lfe
 	if err := node.Decode(&lfev); err != nil {
 		return err
 	}
 
-	if lfev.FromEnv == nil {
+	var src string
+	switch {
+	case lfev.FromEnv != nil && lfev.FromSecretFile != nil:
+		return errors.New("from_env and from_secret_file are mutually exclusive")
+
+	case lfev.FromEnv != nil:
+		fe.Name = *lfev.FromEnv
+		fe.Content = os.Getenv(*lfev.FromEnv)
+		src = "environment variable " + fe.Name
+
+	case lfev.FromSecretFile != nil:
+		fe.SecretFile = *lfev.FromSecretFile
+		content, err := readSecretFile(fe.SecretFile)
+		if err != nil {
+			return err
+		}
+		fe.Content = content
+		src = "secret file " + fe.SecretFile
+
+	default:
 		return nil
 	}
 
-	fe.Content = os.Getenv(*lfev.FromEnv)
+	fe.Default = lfev.Default
+	fe.Required = lfev.Required
 	if len(fe.Content) == 0 {
-		return fmt.Errorf("empty value from environment variable %s", *lfev.FromEnv)
+		if fe.Default != nil {
+			fe.Content = *fe.Default
+			return nil
+		}
+		if fe.Required == nil || *fe.Required {
+			return fmt.Errorf("empty value from %s", src)
+		}
 	}
 	return nil
 }
 
+// readSecretFile returns the content of a secret file without its trailing newline.
+// Relative paths are relative to Directory.
+// With StrictSecrets, files readable by anyone are rejected.
+func readSecretFile(p string) (string, error) {
+	p = resolvePath(p)
+	if StrictSecrets {
+		info, err := os.Stat(p)
+		if err != nil {
+			return "", errors.Wrap(err, "reading secret file")
+		}
+		if info.Mode().Perm()&0004 != 0 {
+			return "", fmt.Errorf("secret file %s is world-readable (%s)", p, info.Mode().Perm())
+		}
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return "", errors.Wrap(err, "reading secret file")
+	}
+	content := strings.TrimSuffix(string(b), "\n")
+	content = strings.TrimSuffix(content, "\r")
+	return content, nil
+}
+
+func (fe FromEnv) MarshalYAML() (any, error) {
+	if fe.Encrypted != "" {
+		return fe.Encrypted, nil
+	}
+	if fe.Name != "" || fe.SecretFile != "" {
+		m := map[string]any{}
+		if fe.Name != "" {
+			m["from_env"] = fe.Name
+		} else {
+			m["from_secret_file"] = fe.SecretFile
+		}
+		if fe.Default != nil {
+			m["default"] = *fe.Default
+		}
+		if fe.Required != nil {
+			m["required"] = *fe.Required
+		}
+		return m, nil
+	}
+	return fe.Content, nil
+}
+
+// ptr returns a pointer to the content, or nil if not defined.
+func (fe *FromEnv) ptr() *string {
+	if fe == nil {
+		return nil
+	}
+	return &fe.Content
+}
+
 func (fe FromEnv) JSONSchema() *jsonschema.Schema {
 	subObj := jsonschema.NewProperties()
 	subObj.Set("from_env", &jsonschema.Schema{
 		Type:        "string",
 		Description: "The environment variable to look at",
 	})
+	subObj.Set("from_secret_file", &jsonschema.Schema{
+		Type:        "string",
+		Description: "The secret file to read (e.g. a Docker or Kubernetes secret), without its trailing newline",
+	})
+	subObj.Set("default", &jsonschema.Schema{
+		Type:        "string",
+		Description: "The content if the environment variable or secret file is not defined or empty",
+	})
+	subObj.Set("required", &jsonschema.Schema{
+		Type:        "boolean",
+		Description: "Whether the environment variable or secret file must be defined, unless it has a default",
+		Default:     true,
+	})
 
 	return &jsonschema.Schema{
 		OneOf: []*jsonschema.Schema{
@@ -0,0 +1,83 @@
+package ctfdsetup_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_U_FromSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private")
+	require.NoError(t, os.WriteFile(private, []byte("s3cr3t\n"), 0600))
+	public := filepath.Join(dir, "public")
+	require.NoError(t, os.WriteFile(public, []byte("s3cr3t\r\n"), 0644))
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte("\n"), 0600))
+
+	var tests = map[string]struct {
+		Content   string
+		Strict    bool
+		Expected  string
+		ExpectErr bool
+	}{
+		"private": {
+			Content:  "from_secret_file: " + private,
+			Expected: "s3cr3t",
+		},
+		"private-strict": {
+			Content:  "from_secret_file: " + private,
+			Strict:   true,
+			Expected: "s3cr3t",
+		},
+		"public": {
+			Content:  "from_secret_file: " + public,
+			Expected: "s3cr3t",
+		},
+		"public-strict": {
+			Content:   "from_secret_file: " + public,
+			Strict:    true,
+			ExpectErr: true,
+		},
+		"empty": {
+			Content:   "from_secret_file: " + empty,
+			ExpectErr: true,
+		},
+		"empty-default": {
+			Content:  "{from_secret_file: " + empty + ", default: fallback}",
+			Expected: "fallback",
+		},
+		"missing": {
+			Content:   "from_secret_file: " + filepath.Join(dir, "missing"),
+			ExpectErr: true,
+		},
+		"exclusive": {
+			Content:   "{from_secret_file: " + private + ", from_env: SECRET}",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			ctfdsetup.StrictSecrets = tt.Strict
+			t.Cleanup(func() {
+				ctfdsetup.StrictSecrets = false
+			})
+
+			fe := ctfdsetup.FromEnv{}
+			err := yaml.Unmarshal([]byte(tt.Content), &fe)
+
+			if tt.ExpectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.Expected, fe.Content)
+		})
+	}
+}
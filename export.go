@@ -0,0 +1,452 @@
+package ctfdsetup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// assetsDir is the directory exported files are written to, relative
+	// to the configuration file.
+	assetsDir = "assets"
+)
+
+// Export reads the configuration of a running CTFd instance.
+//
+// Files (theme images, pages content and uploads) are named relative to
+// the directory the configuration is written to, refer to WriteConfig.
+// Secrets can't be exported thus are replaced by from_env placeholders.
+func Export(ctx context.Context, url, apiKey string, admin Admin, opts ...Option) (*Config, error) {
+	ctx, span := getTracer(opts...).Start(ctx, "Export")
+	defer span.End()
+
+	client, b, err := connect(ctx, url, apiKey, &Config{Admin: admin}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if b {
+		return nil, errors.New("CTFd instance is not setup, nothing to export")
+	}
+
+	// Configs attributes
+	current, err := getConfigs(ctx, client, opts...)
+	if err != nil {
+		return nil, err
+	}
+	conf := exportConfig(paramsFromConfigs(current))
+
+	// Administrator
+	me, err := client.GetUsersMe(ctx, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	conf.Admin = Admin{
+		Name:     FromEnv{Content: me.Name},
+		Email:    FromEnv{Content: deref(me.Email)},
+		Password: FromEnv{Name: "ADMIN_PASSWORD"},
+	}
+
+	// Theme images
+	if conf.Theme.Logo, err = exportFile(ctx, client, current["ctf_logo"], "theme", opts...); err != nil {
+		return nil, errors.Wrap(err, "exporting theme logo")
+	}
+	if conf.Theme.SmallIcon, err = exportFile(ctx, client, current["ctf_small_icon"], "theme", opts...); err != nil {
+		return nil, errors.Wrap(err, "exporting theme small icon")
+	}
+
+	// Additional pages
+	if conf.Pages.Additional, err = exportPages(ctx, client, opts...); err != nil {
+		return nil, err
+	}
+
+	// Uploads, except theme images that are already exported
+	fs, err := client.GetFiles(ctx, &api.GetFilesParams{
+		Type: ptr("standard"),
+	}, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	for _, f := range fs {
+		if f.Location == current["ctf_logo"] || f.Location == current["ctf_small_icon"] {
+			continue
+		}
+		file, err := exportFile(ctx, client, f.Location, "uploads", opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "exporting upload %s", f.Location)
+		}
+		conf.Uploads = append(conf.Uploads, &Upload{
+			File:     file,
+			Location: f.Location,
+		})
+	}
+
+	pruneEmptySections(conf)
+	return conf, nil
+}
+
+// paramsFromConfigs maps back the CTFd configs to the attributes they are patched from.
+// Values that can't be parsed to the attribute type are ignored.
+func paramsFromConfigs(current map[string]string) *api.PatchConfigsParams {
+	params := &api.PatchConfigsParams{}
+	v := reflect.ValueOf(params).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if k, ok := misnamedConfigs[key]; ok {
+			key = k
+		}
+		value, ok := current[key]
+		if !ok || value == "" {
+			continue
+		}
+
+		f := v.Field(i)
+		t := f.Type()
+		isPtr := t.Kind() == reflect.Pointer
+		if isPtr {
+			t = t.Elem()
+		}
+
+		var x reflect.Value
+		switch t.Kind() {
+		case reflect.String:
+			x = reflect.ValueOf(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				continue
+			}
+			x = reflect.ValueOf(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			x = reflect.ValueOf(n)
+		default:
+			continue
+		}
+
+		if isPtr {
+			p := reflect.New(t)
+			p.Elem().Set(x)
+			f.Set(p)
+		} else {
+			f.Set(x)
+		}
+	}
+	return params
+}
+
+// exportConfig is the reverse of configParams.
+func exportConfig(p *api.PatchConfigsParams) *Config {
+	color, header := splitThemeHeader(deref(p.ThemeHeader))
+	conf := &Config{
+		Appearance: Appearance{
+			Name:          deref(p.CTFName),
+			Description:   deref(p.CTFDescription),
+			DefaultLocale: p.DefaultLocale,
+		},
+		Theme: &Theme{
+			Name:     deref(p.CTFTheme),
+			Color:    color,
+			Header:   textFile(&header),
+			Footer:   textFile(p.ThemeFooter),
+			Settings: textFile(p.ThemeSettings),
+		},
+		Accounts: &Accounts{
+			DomainWhitelist:               p.DomainWhitelist,
+			DomainBlacklist:               p.DomainBlacklist,
+			VerifyEmails:                  deref(p.VerifyEmails),
+			TeamCreation:                  p.TeamCreation,
+			TeamSize:                      p.TeamSize,
+			PasswordMinLength:             p.PasswordMinLength,
+			NumTeams:                      p.NumTeams,
+			NumUsers:                      p.NumUsers,
+			TeamDisbanding:                p.TeamDisbanding,
+			IncorrectSubmissionsPerMinute: p.IncorrectSubmissionsPerMin,
+			NameChanges:                   p.NameChanges,
+		},
+		Challenges: &Challenges{
+			ViewSelfSubmission:    p.ViewSelfSubmission,
+			MaxAttemptsBehavior:   orDefault(p.MaxAttemptsBehavior, "lockout"),
+			MaxAttemptsTimeout:    p.MaxAttemptsTimeout,
+			HintsFreePublicAccess: p.HintsFreePublicAccess,
+			ChallengeRatings:      orDefault(p.ChallengeRatings, "public"),
+		},
+		Pages: &Pages{
+			RobotsTxt: textFile(p.RobotsTxt),
+		},
+		MajorLeagueCyber: &MajorLeagueCyber{
+			ClientID:     p.OauthClientID,
+			ClientSecret: secret(p.OauthClientSecret, "MAJOR_LEAGUE_CYBER_CLIENT_SECRET"),
+		},
+		Settings: &Settings{
+			ChallengeVisibility:    orDefault(deref(p.ChallengeVisibility), "private"),
+			AccountVisibility:      orDefault(deref(p.AccountVisibility), "public"),
+			ScoreVisibility:        orDefault(deref(p.ScoreVisibility), "public"),
+			RegistrationVisibility: orDefault(deref(p.RegistrationVisibility), "public"),
+			Paused:                 p.Paused,
+		},
+		Security: &Security{
+			HTMLSanitization: p.HTMLSanitization,
+			RegistrationCode: secret(p.RegistrationCode, "SECURITY_REGISTRATION_CODE"),
+		},
+		Email: &Email{
+			Registration: EmailContent{
+				Subject: p.SuccessfulRegistrationEmailSubject,
+				Body:    p.SuccessfulRegistrationEmailBody,
+			},
+			Confirmation: EmailContent{
+				Subject: p.VerificationEmailSubject,
+				Body:    p.VerificationEmailBody,
+			},
+			NewAccount: EmailContent{
+				Subject: p.UserCreationEmailSubject,
+				Body:    p.UserCreationEmailBody,
+			},
+			PasswordReset: EmailContent{
+				Subject: p.PasswordChangeAlertSubject,
+				Body:    p.PasswordChangeAlertBody,
+			},
+			PasswordResetConfirmation: EmailContent{
+				Subject: p.PasswordResetSubject,
+				Body:    p.PasswordResetBody,
+			},
+			From:     p.MailFromAddr,
+			Server:   p.MailServer,
+			Port:     p.MailPort,
+			Username: p.MailUsername,
+			Password: secret(p.MailPassword, "EMAIL_PASSWORD"),
+			TLS_SSL:  p.MailSSL,
+			STARTTLS: p.MailTLS,
+		},
+		Time: &Time{
+			Start:     p.Start,
+			End:       p.End,
+			Freeze:    p.Freeze,
+			ViewAfter: p.ViewAfterCTF,
+		},
+		Social: &Social{
+			Shares:   p.SocialShares,
+			Template: textFile(&p.SocialSharesTemplate),
+		},
+		Legal: &Legal{
+			TOS: ExternalReference{
+				URL:     p.TOSURL,
+				Content: textFile(p.TOSText),
+			},
+			PrivacyPolicy: ExternalReference{
+				URL:     p.PrivacyURL,
+				Content: textFile(p.PrivacyText),
+			},
+		},
+		Mode: deref(p.UserMode),
+	}
+	return conf
+}
+
+// exportFile downloads the file at the given location, and names it
+// under the assets directory.
+func exportFile(ctx context.Context, client *Client, location, dir string, opts ...Option) (*File, error) {
+	if location == "" {
+		return nil, nil
+	}
+	if !filepath.IsLocal(location) {
+		return nil, fmt.Errorf("invalid file location %s", location)
+	}
+
+	content, err := client.GetFileContent(ctx, &api.File{
+		Location: location,
+	}, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	return &File{
+		Name:    path.Join(assetsDir, dir, location),
+		Content: content,
+	}, nil
+}
+
+// exportPages reads the CTFd pages and names their content under the assets directory.
+func exportPages(ctx context.Context, client *Client, opts ...Option) ([]Page, error) {
+	ctfdPages, err := client.GetPages(ctx, nil, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+
+	pages := make([]Page, 0, len(ctfdPages))
+	for _, ctfdP := range ctfdPages {
+		if !filepath.IsLocal(ctfdP.Route) {
+			return nil, fmt.Errorf("invalid page route %s", ctfdP.Route)
+		}
+
+		// Content is not listed, thus get the page itself
+		p, err := client.GetPage(ctx, ctfdP.ID, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+
+		ext := ".md"
+		if p.Format == "html" {
+			ext = ".html"
+		}
+		pages = append(pages, Page{
+			Title:  p.Title,
+			Route:  p.Route,
+			Format: p.Format,
+			Content: &File{
+				Name:    path.Join(assetsDir, "pages", p.Route+ext),
+				Content: []byte(deref(p.Content)),
+			},
+			Draft:        p.Draft,
+			Hidden:       p.Hidden,
+			AuthRequired: p.AuthRequired,
+		})
+	}
+	return pages, nil
+}
+
+// WriteConfig writes the configuration as YAML in the given directory, along with
+// all the files it refers to by name (e.g. the ones produced by Export).
+func WriteConfig(conf *Config, dir, name string) error {
+	for _, f := range namedFiles(reflect.ValueOf(conf)) {
+		p := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, f.Content, 0o600); err != nil {
+			return errors.Wrapf(err, "writing file %s", p)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(conf); err != nil {
+		return errors.Wrap(err, "marshalling configuration")
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o600)
+}
+
+// namedFiles walks through v and returns all the files that have a name.
+func namedFiles(v reflect.Value) []*File {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if f, ok := v.Interface().(*File); ok {
+			if f.Name == "" {
+				return nil
+			}
+			return []*File{f}
+		}
+		return namedFiles(v.Elem())
+
+	case reflect.Struct:
+		files := []*File{}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				files = append(files, namedFiles(v.Field(i))...)
+			}
+		}
+		return files
+
+	case reflect.Slice, reflect.Array:
+		files := []*File{}
+		for i := 0; i < v.Len(); i++ {
+			files = append(files, namedFiles(v.Index(i))...)
+		}
+		return files
+
+	case reflect.Map:
+		files := []*File{}
+		iter := v.MapRange()
+		for iter.Next() {
+			files = append(files, namedFiles(iter.Value())...)
+		}
+		return files
+	}
+	return nil
+}
+
+// pruneEmptySections removes the configuration sections that are empty,
+// to keep the exported file as short as possible.
+func pruneEmptySections(conf *Config) {
+	v := reflect.ValueOf(conf).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Pointer || f.IsNil() || f.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		if isEmpty(f.Elem()) {
+			f.SetZero()
+		}
+	}
+}
+
+// isEmpty returns whether all the fields of a struct are zero, recursively.
+func isEmpty(v reflect.Value) bool {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			if !isEmpty(f) {
+				return false
+			}
+			continue
+		}
+		if !f.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// textFile returns an inline file of the content, or nil if empty.
+func textFile(content *string) *File {
+	if content == nil || *content == "" {
+		return nil
+	}
+	return &File{
+		Content: []byte(*content),
+	}
+}
+
+// secret returns a from_env placeholder if the secret is defined.
+func secret(value *string, env string) *FromEnv {
+	if value == nil {
+		return nil
+	}
+	return &FromEnv{
+		Name: env,
+	}
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func deref[T any](t *T) T {
+	if t == nil {
+		var zero T
+		return zero
+	}
+	return *t
+}
@@ -0,0 +1,27 @@
+package ctfdsetup
+
+// ConfigValues exposes configValues to tests.
+var ConfigValues = configValues
+
+// DueNotifications exposes dueNotifications to tests.
+var DueNotifications = dueNotifications
+
+// WriteToken and ReuseToken expose writeToken and reuseToken to tests.
+var (
+	WriteToken = writeToken
+	ReuseToken = reuseToken
+)
+
+// All exposes the challenges to manage, ctfcli ones included, to tests.
+func (challs *Challenges) All() ([]*Challenge, error) {
+	return challs.all()
+}
+
+// ExportConfig exposes exportConfig to tests.
+var ExportConfig = exportConfig
+
+// HideDecrypted and Redact expose hideDecrypted and redact to tests.
+var (
+	HideDecrypted = hideDecrypted
+	Redact        = redact
+)
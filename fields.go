@@ -0,0 +1,314 @@
+package ctfdsetup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// fieldsState is the state key of the custom fields IDs managed by ctfd-setup.
+const fieldsState = "fields"
+
+// validateFields checks the custom fields names are unique per type.
+func validateFields(fields *Fields) error {
+	if fields == nil {
+		return nil
+	}
+	var merr error
+	for typ, list := range fields.byType() {
+		names := map[string]struct{}{}
+		for _, f := range list {
+			if _, ok := names[f.Name]; ok {
+				merr = multierr.Append(merr, fmt.Errorf("%s field %s: duplicated name", typ, f.Name))
+			}
+			names[f.Name] = struct{}{}
+		}
+	}
+	return merr
+}
+
+// byType returns the custom fields by CTFd type (user or team).
+func (fields *Fields) byType() map[string][]*CustomField {
+	return map[string][]*CustomField{
+		"user": fields.Users,
+		"team": fields.Teams,
+	}
+}
+
+// updateFields creates and updates the configured custom fields by name, then
+// deletes the managed ones that were removed if asked to.
+// A field players already filled is only deleted when forced.
+// Fields that ctfd-setup did not create are never modified nor deleted, but
+// reported as conflicting.
+func updateFields(ctx context.Context, client *Client, fields *Fields, current map[string]string, opts ...Option) error {
+	// Nothing to manage
+	if len(fields.Users) == 0 && len(fields.Teams) == 0 && !fields.Prune {
+		return nil
+	}
+
+	managed := []int{}
+	if err := readState(current, fieldsState, &managed); err != nil {
+		return err
+	}
+
+	ctfdFields, err := client.GetConfigsFields(ctx, opts...)
+	if err != nil {
+		return &ErrClient{err: err}
+	}
+
+	// Forget the managed fields that were deleted by other means
+	manifest := slices.DeleteFunc(slices.Clone(managed), func(id int) bool {
+		return !slices.ContainsFunc(ctfdFields, func(f *api.ConfigField) bool {
+			return f.ID == id
+		})
+	})
+
+	var merr error
+	for typ, list := range fields.byType() {
+		for _, f := range list {
+			ctfdF := findField(ctfdFields, typ, f.Name)
+			if ctfdF == nil {
+				// CREATE
+				Log().Info(ctx, "creating custom field",
+					zap.String("type", typ),
+					zap.String("name", f.Name),
+				)
+				ctfdF, err := client.PostConfigFields(ctx, &api.PostConfigFieldsParams{
+					Name:        f.Name,
+					Description: f.Description,
+					FieldType:   fieldType(f),
+					Editable:    f.Editable,
+					Public:      f.Public,
+					Required:    f.Required,
+					Type:        typ,
+				}, opts...)
+				if err != nil {
+					merr = multierr.Append(merr, errors.Wrapf(err, "creating %s field %s", typ, f.Name))
+					continue
+				}
+				manifest = append(manifest, ctfdF.ID)
+				continue
+			}
+
+			// CONFLICT
+			if !slices.Contains(manifest, ctfdF.ID) {
+				Log().Error(ctx, "custom field is not managed by ctfd-setup, skipping it",
+					zap.String("type", typ),
+					zap.String("name", f.Name),
+					zap.Int("id", ctfdF.ID),
+				)
+				merr = multierr.Append(merr, fmt.Errorf("%s field %s conflicts with an unmanaged field (id %d)", typ, f.Name, ctfdF.ID))
+				continue
+			}
+
+			// UPDATE
+			if len(diffField(ctfdF, typ, f)) != 0 {
+				Log().Info(ctx, "updating custom field",
+					zap.String("type", typ),
+					zap.String("name", f.Name),
+					zap.Int("id", ctfdF.ID),
+				)
+				if _, err := client.PatchConfigsField(ctx, ctfdF.ID, &api.PatchConfigsFieldParams{
+					ID:          ctfdF.ID,
+					Name:        f.Name,
+					Description: f.Description,
+					FieldType:   fieldType(f),
+					Type:        typ,
+					Editable:    f.Editable,
+					Public:      f.Public,
+					Required:    f.Required,
+				}, opts...); err != nil {
+					merr = multierr.Append(merr, errors.Wrapf(err, "updating %s field %s", typ, f.Name))
+					continue
+				}
+			}
+		}
+	}
+
+	// DELETE
+	if fields.Prune {
+		for _, ctfdF := range prunedFields(manifest, ctfdFields, fields) {
+			name := deref(ctfdF.Name)
+			if !fields.Force {
+				n, err := fieldValues(ctx, client, ctfdF, opts...)
+				if err != nil {
+					merr = multierr.Append(merr, errors.Wrapf(err, "counting %s field %s values", ctfdF.Type, name))
+					continue
+				}
+				if n != 0 {
+					Log().Error(ctx, "custom field has values, skipping its deletion",
+						zap.String("type", ctfdF.Type),
+						zap.String("name", name),
+						zap.Int("values", n),
+					)
+					merr = multierr.Append(merr, fmt.Errorf("%s field %s has %d values, set fields.force to delete it anyway", ctfdF.Type, name, n))
+					continue
+				}
+			}
+
+			Log().Info(ctx, "deleting custom field",
+				zap.String("type", ctfdF.Type),
+				zap.String("name", name),
+				zap.Int("id", ctfdF.ID),
+			)
+			if err := client.DeleteConfigsField(ctx, ctfdF.ID, opts...); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "deleting %s field %s", ctfdF.Type, name))
+				continue
+			}
+			manifest = slices.DeleteFunc(manifest, func(id int) bool {
+				return id == ctfdF.ID
+			})
+		}
+	}
+
+	// Save the manifest if it changed
+	slices.Sort(manifest)
+	slices.Sort(managed)
+	if !slices.Equal(manifest, managed) {
+		if err := writeState(ctx, client, fieldsState, manifest, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrap(err, "saving fields manifest"))
+		}
+	}
+	return merr
+}
+
+// diffFields compares the CTFd custom fields to the configured ones, per attribute.
+func diffFields(ctx context.Context, client *Client, bare bool, fields *Fields, current map[string]string, opts ...Option) ([]*Diff, error) {
+	// Nothing to manage
+	if len(fields.Users) == 0 && len(fields.Teams) == 0 && !fields.Prune {
+		return nil, nil
+	}
+
+	ctfdFields := []*api.ConfigField{}
+	managed := []int{}
+	if !bare {
+		var err error
+		ctfdFields, err = client.GetConfigsFields(ctx, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		if err := readState(current, fieldsState, &managed); err != nil {
+			return nil, err
+		}
+	}
+
+	diffs := []*Diff{}
+	for typ, list := range fields.byType() {
+		for _, f := range list {
+			ctfdF := findField(ctfdFields, typ, f.Name)
+			if ctfdF == nil {
+				diffs = append(diffs, &Diff{
+					Kind:    DiffAdded,
+					Key:     "fields." + typ + "." + f.Name,
+					Desired: f.Name,
+				})
+				continue
+			}
+			if !slices.Contains(managed, ctfdF.ID) {
+				diffs = append(diffs, &Diff{
+					Kind:    DiffChanged,
+					Key:     "fields." + typ + "." + f.Name,
+					Current: "(unmanaged) " + deref(ctfdF.Name),
+					Desired: f.Name,
+				})
+				continue
+			}
+			diffs = append(diffs, diffField(ctfdF, typ, f)...)
+		}
+	}
+
+	if fields.Prune {
+		for _, ctfdF := range prunedFields(managed, ctfdFields, fields) {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffRemoved,
+				Key:     "fields." + ctfdF.Type + "." + deref(ctfdF.Name),
+				Current: deref(ctfdF.Name),
+			})
+		}
+	}
+	return diffs, nil
+}
+
+// diffField compares the attributes of a CTFd custom field to the configured ones.
+func diffField(ctfdF *api.ConfigField, typ string, f *CustomField) []*Diff {
+	key := "fields." + typ + "." + f.Name
+	diffs := []*Diff{}
+	for _, attr := range []struct {
+		name             string
+		current, desired string
+	}{
+		{"description", deref(ctfdF.Description), f.Description},
+		{"type", fmt.Sprint(ctfdF.FieldType), fieldType(f)},
+		{"editable", strconv.FormatBool(ctfdF.Editable), strconv.FormatBool(f.Editable)},
+		{"required", strconv.FormatBool(ctfdF.Required), strconv.FormatBool(f.Required)},
+		{"public", strconv.FormatBool(ctfdF.Public), strconv.FormatBool(f.Public)},
+	} {
+		if attr.current == attr.desired {
+			continue
+		}
+		diffs = append(diffs, &Diff{
+			Kind:    DiffChanged,
+			Key:     key + "." + attr.name,
+			Current: attr.current,
+			Desired: attr.desired,
+		})
+	}
+	return diffs
+}
+
+// fieldValues counts the users or teams that filled the custom field.
+func fieldValues(ctx context.Context, client *Client, ctfdF *api.ConfigField, opts ...Option) (int, error) {
+	n := 0
+	for page := 1; ; page++ {
+		accounts, err := client.GetAccountsFields(ctx, ctfdF.Type, page, opts...)
+		if err != nil {
+			return 0, &ErrClient{err: err}
+		}
+		if len(accounts) == 0 {
+			return n, nil
+		}
+		for _, acc := range accounts {
+			for _, entry := range acc.Fields {
+				if entry.FieldID == ctfdF.ID && entry.Value != nil && entry.Value != "" {
+					n++
+				}
+			}
+		}
+	}
+}
+
+// findField returns the CTFd custom field of the given type and name, if any.
+func findField(ctfdFields []*api.ConfigField, typ, name string) *api.ConfigField {
+	for _, f := range ctfdFields {
+		if f.Type == typ && deref(f.Name) == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// prunedFields returns the managed CTFd custom fields that are not configured anymore.
+func prunedFields(managed []int, ctfdFields []*api.ConfigField, fields *Fields) []*api.ConfigField {
+	pruned := []*api.ConfigField{}
+	for _, ctfdF := range ctfdFields {
+		if !slices.Contains(managed, ctfdF.ID) {
+			continue
+		}
+		if !slices.ContainsFunc(fields.byType()[ctfdF.Type], func(f *CustomField) bool {
+			return f.Name == deref(ctfdF.Name)
+		}) {
+			pruned = append(pruned, ctfdF)
+		}
+	}
+	return pruned
+}
+
+func fieldType(f *CustomField) string {
+	return orDefault(f.Type, "text")
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/invopop/jsonschema"
+	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,12 +16,26 @@ var (
 type File struct {
 	Name    string `yaml:"-" json:"-" jsonschema:"-"`
 	Content []byte `yaml:"-" json:"-" jsonschema:"-"`
+
+	// Encrypted is the age-encrypted armored content, if any
+	Encrypted string `yaml:"-" json:"-" jsonschema:"-"`
 }
 
 var _ yaml.Unmarshaler = (*File)(nil)
+var _ yaml.Marshaler = (*File)(nil)
+var _ yaml.IsZeroer = (*File)(nil)
 
 func (file *File) UnmarshalYAML(node *yaml.Node) error {
 	if node.Value != "" {
+		if isEncrypted(node.Value) {
+			b, err := decrypt(node.Value)
+			if err != nil {
+				return errors.Wrapf(err, "line %d", node.Line)
+			}
+			file.Encrypted = node.Value
+			file.Content = b
+			return nil
+		}
 		file.Content = []byte(node.Value)
 		return nil
 	}
@@ -36,15 +51,39 @@ func (file *File) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 	}
 
-	fc, err := os.ReadFile(filepath.Join(Directory, *lfiv.FromFile))
+	fc, err := os.ReadFile(resolvePath(*lfiv.FromFile))
 	if err != nil {
-		return err
+		return errors.Wrapf(err, "line %d", node.Line)
 	}
 	file.Name = *lfiv.FromFile
 	file.Content = fc
 	return nil
 }
 
+// resolvePath returns the path relative to Directory, unless absolute.
+func resolvePath(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(Directory, p)
+}
+
+func (file File) MarshalYAML() (any, error) {
+	if file.Encrypted != "" {
+		return file.Encrypted, nil
+	}
+	if file.Name != "" {
+		return map[string]string{
+			"from_file": file.Name,
+		}, nil
+	}
+	return string(file.Content), nil
+}
+
+func (file File) IsZero() bool {
+	return file.Name == "" && len(file.Content) == 0
+}
+
 func (file File) JSONSchema() *jsonschema.Schema {
 	subObj := jsonschema.NewProperties()
 	subObj.Set("from_file", &jsonschema.Schema{
@@ -3,6 +3,7 @@ module github.com/ctfer-io/ctfd-setup
 go 1.25.4
 
 require (
+	filippo.io/age v1.2.1
 	github.com/ctfer-io/go-ctfd v0.16.0
 	github.com/invopop/jsonschema v0.14.0
 	github.com/pkg/errors v0.9.1
@@ -63,6 +64,7 @@ require (
 	go.opentelemetry.io/proto/otlp v1.10.0 // indirect
 	go.yaml.in/yaml/v2 v2.4.4 // indirect
 	go.yaml.in/yaml/v4 v4.0.0-rc.2 // indirect
+	golang.org/x/crypto v0.51.0 // indirect
 	golang.org/x/net v0.55.0 // indirect
 	golang.org/x/sys v0.47.0 // indirect
 	golang.org/x/text v0.37.0 // indirect
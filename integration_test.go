@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	ctfdsetup "github.com/ctfer-io/ctfd-setup"
 	"github.com/ctfer-io/go-ctfd/api"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace/noop"
 	"gopkg.in/yaml.v3"
 )
@@ -60,6 +66,71 @@ func Test_I_Minimal(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func Test_I_TokenBootstrap(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	// The bare setup bootstraps an API token for automation
+	conf.Token.Output = ctfdsetup.TokenOutputFile
+	conf.Token.File = filepath.Join(t.TempDir(), ".ctfd-token")
+	conf.Token.Expiration = "2222-01-01"
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.FileExists(t, conf.Token.File)
+
+	// Later runs reuse it, without the administrator password
+	conf.Admin.Password.Content = ""
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+}
+
+func Test_I_AdminRotation(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// The administrator password leaked, thus gets rotated
+	conf.Admin.Rotate.Password = &ctfdsetup.FromEnv{Content: "rotated"}
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// Re-running logs in with the rotated credentials, as the old ones got rejected
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// Rotate back for the cleanup to log in
+	conf.Admin.Password.Content = "rotated"
+	conf.Admin.Rotate.Password = &ctfdsetup.FromEnv{Content: "ctfer"}
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+}
+
 func Test_I_NoFile(t *testing.T) {
 	t.Cleanup(func() {
 		require.NoError(t, reset(context.WithoutCancel(t.Context())))
@@ -159,10 +230,409 @@ func Test_I_Tracing(t *testing.T) {
 	require.NoError(t, err)
 }
 
-func reset(ctx context.Context) error {
+func Test_I_Plan(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	// Once setup, the instance matches the configuration
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	diffs, err := ctfdsetup.Plan(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+
+	// Then a change in the configuration is reported
+	conf.Appearance.Name = "MyCTF 20YY"
+
+	diffs, err = ctfdsetup.Plan(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+	require.Equal(t, ctfdsetup.DiffChanged, diffs[0].Kind)
+	require.Equal(t, "ctf_name", diffs[0].Key)
+}
+
+func Test_I_NoChangeNoWrite(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// Re-running with no changes patches no config
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf, ctfdsetup.WithTracerProvider(tp))
+	require.NoError(t, err)
+
+	for _, span := range sr.Ended() {
+		require.NotEqual(t, "api/PatchConfigsValues", span.Name())
+		if span.Name() == "Setup" {
+			for _, attr := range span.Attributes() {
+				if attr.Key == "ctfd.configs.changed" {
+					require.Empty(t, attr.Value.AsStringSlice())
+				}
+			}
+		}
+	}
+}
+
+func Test_I_Check(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(t.Context())))
+	})
+
+	args := []string{
+		"--url", CTFdURL,
+		"--directory", "examples/minimal",
+		"--file", "examples/minimal/.ctfd.yaml",
+	}
+
+	// Setup the instance
+	cmd := exec.CommandContext(t.Context(), "./ctfd-setup", args...)
+	cmd.Env = envs
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	// No drift
+	cmd = exec.CommandContext(t.Context(), "./ctfd-setup", append([]string{"check"}, args...)...)
+	cmd.Env = envs
+	out, err = cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	// Drift on the CTF name
+	cmd = exec.CommandContext(t.Context(), "./ctfd-setup", append([]string{"check", "--appearance.name", "Drifted"}, args...)...)
+	cmd.Env = envs
+	out, err = cmd.CombinedOutput()
+	exitErr := &exec.ExitError{}
+	require.ErrorAs(t, err, &exitErr, string(out))
+	require.Equal(t, 2, exitErr.ExitCode())
+	require.Contains(t, string(out), "ctf_name")
+}
+
+func Test_I_Export(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+	conf.Security.RegistrationCode = &ctfdsetup.FromEnv{Content: "registration-s3cr3t"}
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// Export the instance and write it back to files
+	exp, err := ctfdsetup.Export(ctx, CTFdURL, "", conf.Admin)
+	require.NoError(t, err)
+	require.Equal(t, conf.Appearance.Name, exp.Appearance.Name)
+	require.Equal(t, "ADMIN_PASSWORD", exp.Admin.Password.Name)
+
+	dir := t.TempDir()
+	err = ctfdsetup.WriteConfig(exp, dir, ".ctfd.yaml")
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, ".ctfd.yaml"))
+
+	// Secrets are never exported in clear text
+	require.Equal(t, "SECURITY_REGISTRATION_CODE", exp.Security.RegistrationCode.Name)
+	b, err := os.ReadFile(filepath.Join(dir, ".ctfd.yaml"))
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "registration-s3cr3t")
+
+	info, err := os.Stat(filepath.Join(dir, ".ctfd.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func Test_I_ThemeImages(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	conf.Theme.Logo = &ctfdsetup.File{
+		Name:    "logo.png",
+		Content: []byte("not really a PNG"),
+	}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	client, err := login(ctx)
+	require.NoError(t, err)
+	location := getConfig(t, client, "ctf_logo")
+	require.NotEmpty(t, location)
+
+	// Re-running does not upload the logo again
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Equal(t, location, getConfig(t, client, "ctf_logo"))
+
+	// Removing it deletes the file
+	conf.Theme.Logo = &ctfdsetup.File{}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Empty(t, getConfig(t, client, "ctf_logo"))
+
+	fs, err := client.GetFiles(&api.GetFilesParams{
+		Location: &location,
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Empty(t, fs)
+}
+
+func Test_I_PruneUploads(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	conf.UploadsPolicy = ctfdsetup.UploadsPrune
+	conf.Uploads = []*ctfdsetup.Upload{
+		{
+			File: &ctfdsetup.File{
+				Name:    "sponsor.png",
+				Content: []byte("sponsor"),
+			},
+			Location: "sponsors/sponsor.png",
+		},
+	}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// Upload a file by other means, that must not be pruned
+	client, err := login(ctx)
+	require.NoError(t, err)
+	_, err = client.PostFiles(&api.PostFilesParams{
+		Files: []*api.InputFile{
+			{
+				Name:    "other.png",
+				Content: []byte("other"),
+			},
+		},
+		Location: ptr("others/other.png"),
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+
+	// Remove the upload from the configuration
+	conf.Uploads = []*ctfdsetup.Upload{}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	fs, err := client.GetFiles(&api.GetFilesParams{
+		Location: ptr("sponsors/sponsor.png"),
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Empty(t, fs)
+
+	fs, err = client.GetFiles(&api.GetFilesParams{
+		Location: ptr("others/other.png"),
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.NotEmpty(t, fs)
+}
+
+func Test_I_PagesOwnership(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	conf.Pages.Additional = []ctfdsetup.Page{
+		{
+			Title:   "Rules",
+			Route:   "rules",
+			Content: &ctfdsetup.File{Content: []byte("Be nice.")},
+		},
+	}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// Create a page by hand
+	client, err := login(ctx)
+	require.NoError(t, err)
+	_, err = client.PostPages(&api.PostPagesParams{
+		Title:   "Sponsors",
+		Route:   "sponsors",
+		Content: "Thanks!",
+		Format:  "markdown",
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+
+	// Removing the managed page deletes it, but keeps the other one
+	conf.Pages.Additional = []ctfdsetup.Page{}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	pages, err := client.GetPages(nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	routes := []string{}
+	for _, p := range pages {
+		routes = append(routes, p.Route)
+	}
+	require.NotContains(t, routes, "rules")
+	require.Contains(t, routes, "sponsors")
+
+	// Configuring a page at the route of the unmanaged one conflicts
+	conf.Pages.Additional = []ctfdsetup.Page{
+		{
+			Title:   "Our sponsors",
+			Route:   "sponsors",
+			Content: &ctfdsetup.File{Content: []byte("Thanks to them!")},
+		},
+	}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.Error(t, err)
+}
+
+func Test_I_PagesUpgrade(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// Mimic a version of ctfd-setup that did not keep track of the pages
+	client, err := login(ctx)
+	require.NoError(t, err)
+	err = client.Patch("/configs", map[string]any{
+		"ctfd_setup_pages": "",
+	}, nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	_, err = client.PostPages(&api.PostPagesParams{
+		Title:   "Rules",
+		Route:   "rules",
+		Content: "Be nice.",
+		Format:  "markdown",
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+
+	// The existing pages at a configured route are adopted
+	conf.Pages.Additional = []ctfdsetup.Page{
+		{
+			Title:   "Index",
+			Route:   "index",
+			Content: &ctfdsetup.File{Content: []byte("Welcome!")},
+		}, {
+			Title:   "Our rules",
+			Route:   "rules",
+			Content: &ctfdsetup.File{Content: []byte("Be nice, really.")},
+		},
+	}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	pages, err := client.GetPages(nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	titles := []string{}
+	for _, p := range pages {
+		titles = append(titles, p.Title)
+	}
+	require.Contains(t, titles, "Our rules")
+	require.NotEmpty(t, getConfig(t, client, "ctfd_setup_pages"))
+
+	// Without any page to adopt, the manifest is written anyway, such that
+	// a page created by hand later on is not adopted
+	err = client.Patch("/configs", map[string]any{
+		"ctfd_setup_pages": "",
+	}, nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	conf.Pages.Additional = []ctfdsetup.Page{}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.NotEmpty(t, getConfig(t, client, "ctfd_setup_pages"))
+
+	_, err = client.PostPages(&api.PostPagesParams{
+		Title:   "FAQ",
+		Route:   "faq",
+		Content: "Ask us.",
+		Format:  "markdown",
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+	conf.Pages.Additional = []ctfdsetup.Page{{
+		Title:   "FAQ",
+		Route:   "faq",
+		Content: &ctfdsetup.File{Content: []byte("Read the rules.")},
+	}}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.ErrorContains(t, err, "conflicts with an unmanaged page")
+}
+
+func getConfig(t *testing.T, client *api.Client, key string) string {
+	cfgs, err := client.GetConfigs(&api.GetConfigsParams{
+		Key: &key,
+	}, api.WithContext(t.Context()))
+	require.NoError(t, err)
+	if len(cfgs) == 0 {
+		return ""
+	}
+	return cfgs[0].Value
+}
+
+func login(ctx context.Context) (*api.Client, error) {
 	nonce, session, err := api.GetNonceAndSession(CTFdURL, api.WithContext(ctx))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	client := api.NewClient(CTFdURL, nonce, session, "")
@@ -171,6 +641,354 @@ func reset(ctx context.Context) error {
 		Name:     "ctfer",
 		Password: "ctfer",
 	}, api.WithContext(ctx)); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func Test_I_Challenges(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	conf.Challenges.List = []*ctfdsetup.Challenge{
+		{
+			Key:      "warmup",
+			Name:     "Warmup",
+			Category: "misc",
+			Value:    500,
+			Type:     "dynamic",
+			Dynamic: &ctfdsetup.DynamicScoring{
+				Minimum: 50,
+				Decay:   20,
+			},
+			Flags: []*ctfdsetup.Flag{
+				{Content: ctfdsetup.FromEnv{Content: "CTF{warmup}"}},
+			},
+			Hints: []*ctfdsetup.Hint{
+				{Content: "Read the rules", Cost: 10},
+			},
+			Tags: []string{"easy"},
+		},
+	}
+
+	// Running twice does not duplicate the challenge
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	client, err := login(ctx)
+	require.NoError(t, err)
+	challs, err := client.GetChallenges(&api.GetChallengesParams{
+		View: ptr("admin"),
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Len(t, challs, 1)
+	id := challs[0].ID
+
+	diffs, err := ctfdsetup.Plan(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+
+	// Renaming updates it in place
+	conf.Challenges.List[0].Name = "Warm up"
+	conf.Challenges.List[0].Tags = []string{"beginner"}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	chall, err := client.GetChallenge(id, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Equal(t, "Warm up", chall.Name)
+	tags, err := client.GetChallengeTags(id, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	require.Equal(t, "beginner", tags[0].Value)
+
+	// Pruning deletes it
+	conf.Challenges.List = nil
+	conf.Challenges.Prune = true
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	challs, err = client.GetChallenges(&api.GetChallengesParams{
+		View: ptr("admin"),
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Empty(t, challs)
+}
+
+func Test_I_Staff(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	conf.Staff.List = []*ctfdsetup.StaffMember{
+		{Name: "alice", Email: "alice@ctfer.io", Password: ctfdsetup.FromEnv{Content: "alice-password"}},
+		{Name: "bob", Email: "bob@ctfer.io", Password: ctfdsetup.FromEnv{Content: "bob-password"}},
+	}
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	diffs, err := ctfdsetup.Plan(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+
+	// Bob leaves the organization
+	conf.Staff.List = conf.Staff.List[:1]
+	conf.Staff.Prune = true
+
+	diffs, err = ctfdsetup.Plan(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+	require.Equal(t, ctfdsetup.DiffRemoved, diffs[0].Kind)
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	diffs, err = ctfdsetup.Plan(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+
+	// A registered player is not promoted, thus never deleted
+	client, err := login(ctx)
+	require.NoError(t, err)
+	player, err := client.PostUsers(&api.PostUsersParams{
+		Name:     "carol",
+		Email:    "carol@ctfer.io",
+		Password: "carol-password",
+		Type:     "user",
+		Fields:   []api.Field{},
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+
+	conf.Staff.List = append(conf.Staff.List, &ctfdsetup.StaffMember{
+		Name: "carol", Email: "carol@ctfer.io", Password: ctfdsetup.FromEnv{Content: "carol-password"},
+	})
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.ErrorContains(t, err, "conflicts with an unmanaged account")
+
+	conf.Staff.List = conf.Staff.List[:1]
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	u, err := client.GetUser(player.ID, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Equal(t, ptr("user"), u.Type)
+}
+
+func Test_I_BracketsOwnership(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// A bracket created by hand is not adopted
+	client, err := login(ctx)
+	require.NoError(t, err)
+	_, err = client.PostBrackets(&api.PostBracketsParams{
+		Name: "Professionals",
+		Type: conf.Mode,
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+
+	conf.Brackets.List = []*ctfdsetup.Bracket{
+		{Name: "Students"},
+		{Name: "Professionals", Description: "Overwritten"},
+	}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.ErrorContains(t, err, "conflicts with an unmanaged bracket")
+
+	// Thus never deleted
+	conf.Brackets.List = []*ctfdsetup.Bracket{}
+	conf.Brackets.Prune = true
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	bks, err := client.GetBrackets(nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	names := []string{}
+	for _, bk := range bks {
+		names = append(names, bk.Name)
+		if bk.Name == "Professionals" {
+			require.Empty(t, bk.Description)
+		}
+	}
+	require.Contains(t, names, "Professionals")
+	require.NotContains(t, names, "Students")
+}
+
+func Test_I_UsersAndTeams(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	conf.Mode = "teams"
+	conf.Users.List = []*ctfdsetup.User{
+		{Name: "alice", Email: "alice@ctfer.io", Verified: true},
+		{Name: "bob", Email: "bob@ctfer.io", Password: &ctfdsetup.FromEnv{Content: "bob-password"}},
+	}
+	conf.Users.PasswordsFile = filepath.Join(dir, "users-passwords.csv")
+	conf.Teams.List = []*ctfdsetup.Team{
+		{Name: "CTFer", Members: []string{"alice", "bob"}, Captain: ptr("bob")},
+	}
+	conf.Teams.PasswordsFile = filepath.Join(dir, "teams-passwords.csv")
+
+	// Running twice does not duplicate users nor teams
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	diffs, err := ctfdsetup.Plan(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+
+	// Only generated passwords are written, once
+	b, err := os.ReadFile(conf.Users.PasswordsFile)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(string(b)), "\n"), 2) // header + alice
+	b, err = os.ReadFile(conf.Teams.PasswordsFile)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(string(b)), "\n"), 2) // header + CTFer
+
+	client, err := login(ctx)
+	require.NoError(t, err)
+	teams, err := client.GetTeams(nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Len(t, teams, 1)
+	members, err := client.GetTeamMembers(teams[0].ID, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+
+	// Updating a user is done in place
+	conf.Users.List[1].Hidden = true
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	diffs, err = ctfdsetup.Plan(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+}
+
+func Test_I_Fields(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		require.NoError(t, reset(context.WithoutCancel(ctx)))
+	})
+
+	conf := ctfdsetup.NewConfig()
+
+	dec := yaml.NewDecoder(bytes.NewReader(minimalConf))
+	dec.KnownFields(true)
+
+	err := dec.Decode(conf)
+	require.NoError(t, err)
+
+	conf.Fields.Users = []*ctfdsetup.CustomField{
+		{Name: "School", Editable: true},
+	}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	// A player fills the field
+	client, err := login(ctx)
+	require.NoError(t, err)
+	fields, err := client.GetConfigsFields(nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Len(t, fields, 1)
+	_, err = client.PostUsers(&api.PostUsersParams{
+		Name:     "alice",
+		Email:    "alice@ctfer.io",
+		Password: "alice-password",
+		Type:     "user",
+		Fields: []api.Field{
+			{FieldID: fields[0].ID, Value: "ENSIBS"},
+		},
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+
+	// Removing it is refused, unless forced
+	conf.Fields.Users = nil
+	conf.Fields.Prune = true
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.Error(t, err)
+
+	conf.Fields.Force = true
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	fields, err = client.GetConfigsFields(nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Empty(t, fields)
+
+	// A field created by hand is not adopted, thus never deleted
+	website, err := client.PostConfigFields(&api.PostConfigFieldsParams{
+		Name:      "Website",
+		FieldType: "text",
+		Type:      "user",
+	}, api.WithContext(ctx))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.DeleteConfigsField(strconv.Itoa(website.ID), api.WithContext(context.WithoutCancel(ctx)))
+	})
+
+	conf.Fields.Users = []*ctfdsetup.CustomField{{Name: "Website", Description: "Overwritten"}}
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.ErrorContains(t, err, "conflicts with an unmanaged field")
+
+	conf.Fields.Users = nil
+	err = ctfdsetup.Setup(ctx, CTFdURL, "", conf)
+	require.NoError(t, err)
+
+	fields, err = client.GetConfigsFields(nil, api.WithContext(ctx))
+	require.NoError(t, err)
+	require.Len(t, fields, 1)
+}
+
+func reset(ctx context.Context) error {
+	client, err := login(ctx)
+	if err != nil {
 		return err
 	}
 	return client.Reset(&api.ResetParams{
@@ -0,0 +1,277 @@
+package ctfdsetup
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v3"
+)
+
+// envName matches the valid environment variable names.
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// interpolator replaces the environment variables expressions of a YAML document,
+// and collects the missing ones.
+//
+// Any scalar value supports the following expressions:
+//   - ${VAR} is replaced by the value of VAR, or an empty string if not defined ;
+//   - ${VAR:-default} is replaced by the value of VAR, or default if not defined or empty ;
+//   - ${VAR:?} is replaced by the value of VAR, that must be defined and not empty ;
+//   - $$ is replaced by a single $.
+//
+// Interpolated values remain strings, unless plain and defining a boolean or numeric field.
+// All the missing required variables, including the "from_env" ones, are reported at once.
+type interpolator struct {
+	missing []missingVar
+	errs    error
+	// plain are the interpolated plain scalars, that remain strings unless
+	// resolved otherwise
+	plain map[*yaml.Node]struct{}
+}
+
+// missingVar is a required environment variable that is not defined, and the
+// node it is required by.
+type missingVar struct {
+	name string
+	node *yaml.Node
+}
+
+func (in *interpolator) walk(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			in.walk(n)
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			in.walk(node.Content[i+1])
+		}
+		in.fromEnv(node)
+
+	case yaml.ScalarNode:
+		in.scalar(node)
+	}
+}
+
+// scalar interpolates the value of a scalar node.
+// Interpolated values remain strings, until resolve gives their type back to the
+// plain ones.
+func (in *interpolator) scalar(node *yaml.Node) {
+	if !strings.Contains(node.Value, "$") {
+		return
+	}
+
+	out := strings.Builder{}
+	s := node.Value
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			out.WriteString(s)
+			break
+		}
+		out.WriteString(s[:i])
+		s = s[i:]
+
+		switch s[1] {
+		case '$':
+			out.WriteByte('$')
+			s = s[2:]
+
+		case '{':
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				in.errs = multierr.Append(in.errs, fmt.Errorf("line %d: unterminated expression %s", node.Line, s))
+				return
+			}
+			v, err := in.expand(s[2:end], node)
+			if err != nil {
+				in.errs = multierr.Append(in.errs, errors.Wrapf(err, "line %d", node.Line))
+				return
+			}
+			out.WriteString(v)
+			s = s[end+1:]
+
+		default:
+			out.WriteByte('$')
+			s = s[1:]
+		}
+	}
+
+	node.Value = out.String()
+	if node.Style == 0 && node.Tag == "!!str" {
+		if in.plain == nil {
+			in.plain = map[*yaml.Node]struct{}{}
+		}
+		in.plain[node] = struct{}{}
+	}
+}
+
+// resolve resolves again the type of the interpolated plain scalars that
+// target a boolean or numeric field of t, such that e.g. an integer can be
+// defined from an environment variable. Others remain strings, even if the
+// value looks like e.g. null or true.
+func (in *interpolator) resolve(node *yaml.Node, t reflect.Type) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			in.resolve(n, t)
+		}
+
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for _, n := range node.Content {
+				in.resolve(n, t.Elem())
+			}
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			switch t.Kind() {
+			case reflect.Struct:
+				if f, ok := yamlField(t, node.Content[i].Value); ok {
+					in.resolve(node.Content[i+1], f.Type)
+				}
+			case reflect.Map:
+				in.resolve(node.Content[i+1], t.Elem())
+			}
+		}
+
+	case yaml.ScalarNode:
+		if _, ok := in.plain[node]; !ok {
+			return
+		}
+		switch t.Kind() {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			node.Tag = ""
+		}
+	}
+}
+
+// interpolationPattern matches the values that are interpolated.
+const interpolationPattern = `\$\{[A-Za-z_][A-Za-z0-9_]*(:[-?][^}]*)?\}`
+
+// allowInterpolation makes the boolean and numeric properties of the schema
+// also accept an interpolated string, as it is resolved when decoding.
+func allowInterpolation(s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+	for _, def := range s.Definitions {
+		allowInterpolation(def)
+	}
+	allowInterpolation(s.Items)
+	allowInterpolation(s.AdditionalProperties)
+	if s.Properties == nil {
+		return
+	}
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		switch p.Value.Type {
+		case "boolean", "integer", "number":
+			s.Properties.Set(p.Key, &jsonschema.Schema{
+				Description: p.Value.Description,
+				AnyOf: []*jsonschema.Schema{
+					p.Value, {
+						Type:    "string",
+						Pattern: interpolationPattern,
+					},
+				},
+			})
+		default:
+			allowInterpolation(p.Value)
+		}
+	}
+}
+
+// expand returns the value of an expression, without its ${} delimiters.
+func (in *interpolator) expand(expr string, node *yaml.Node) (string, error) {
+	name, def, hasDef := strings.Cut(expr, ":-")
+	required := false
+	if !hasDef {
+		name, _, required = strings.Cut(expr, ":?")
+	}
+	if !envName.MatchString(name) {
+		return "", fmt.Errorf("invalid environment variable name %q", name)
+	}
+
+	v := os.Getenv(name)
+	if v == "" {
+		switch {
+		case hasDef:
+			return def, nil
+		case required:
+			in.missing = append(in.missing, missingVar{name: name, node: node})
+		}
+	}
+	return v, nil
+}
+
+// fromEnv checks the environment variable of a "from_env" mapping is defined,
+// unless it has a default or is not required.
+func (in *interpolator) fromEnv(node *yaml.Node) {
+	var name *yaml.Node
+	hasDef, required := false, true
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		switch k.Value {
+		case "from_env":
+			name = v
+		case "default":
+			hasDef = true
+		case "required":
+			required = v.Value != "false"
+		}
+	}
+	if name == nil || hasDef || !required {
+		return
+	}
+	if os.Getenv(name.Value) == "" {
+		in.missing = append(in.missing, missingVar{name: name.Value, node: name})
+	}
+}
+
+// err returns the interpolation errors, with all the missing variables in a single one.
+// Only the variables required by a node of the document are reported, such that the
+// ones of e.g. the profiles that are not selected are not.
+func (in *interpolator) err(doc *yaml.Node) error {
+	nodes := map[*yaml.Node]struct{}{}
+	collectNodes(doc, nodes)
+
+	missing := []string{}
+	for _, m := range in.missing {
+		if _, ok := nodes[m.node]; ok {
+			missing = append(missing, fmt.Sprintf("%s (line %d)", m.name, m.node.Line))
+		}
+	}
+	if len(missing) == 0 {
+		return in.errs
+	}
+	return multierr.Append(in.errs, fmt.Errorf("missing environment variables: %s", strings.Join(missing, ", ")))
+}
+
+// collectNodes collects the node and its content.
+func collectNodes(node *yaml.Node, nodes map[*yaml.Node]struct{}) {
+	if _, ok := nodes[node]; ok {
+		return
+	}
+	nodes[node] = struct{}{}
+	for _, n := range node.Content {
+		collectNodes(n, nodes)
+	}
+}
@@ -0,0 +1,187 @@
+package ctfdsetup_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func Test_U_LoadConfigInterpolation(t *testing.T) {
+	t.Setenv("CTF_NAME", "My CTF")
+	t.Setenv("TEAM_SIZE", "4")
+	t.Setenv("MAIL_PASSWORD", "s3cr3t")
+	t.Setenv("EMPTY", "")
+	t.Setenv("NULL", "null")
+	t.Setenv("TRUE", "true")
+
+	var tests = map[string]struct {
+		Content   string
+		ExpectErr bool
+		Check     func(t *testing.T, conf *ctfdsetup.Config)
+	}{
+		"interpolation": {
+			Content: `appearance:
+  name: ${CTF_NAME}
+  description: "Welcome to ${CTF_NAME:?}, costs $$5${EMPTY}"
+accounts:
+  team_size: ${TEAM_SIZE}
+email:
+  server: ${MAIL_SERVER:-smtp.example.com}
+  username: $USER
+  password:
+    from_env: MAIL_PASSWORD
+admin:
+  name: admin
+  email: admin@example.com
+  password:
+    from_env: ADMIN_PASSWORD
+    default: changeme
+`,
+			Check: func(t *testing.T, conf *ctfdsetup.Config) {
+				assert.Equal(t, "My CTF", conf.Appearance.Name)
+				assert.Equal(t, "Welcome to My CTF, costs $5", conf.Appearance.Description)
+				require.NotNil(t, conf.Accounts.TeamSize)
+				assert.Equal(t, 4, *conf.Accounts.TeamSize)
+				assert.Equal(t, "smtp.example.com", *conf.Email.Server)
+				assert.Equal(t, "$USER", *conf.Email.Username)
+				assert.Equal(t, "s3cr3t", conf.Email.Password.Content)
+				assert.Equal(t, "changeme", conf.Admin.Password.Content)
+			},
+		},
+		"types": {
+			Content: `appearance:
+  name: ${TRUE}
+  description: ${TEAM_SIZE}
+security:
+  registration_code: ${NULL}
+settings:
+  paused: ${TRUE}
+accounts:
+  team_size: ${TEAM_SIZE}
+admin:
+  name: admin
+  email: admin@example.com
+  password: admin
+`,
+			Check: func(t *testing.T, conf *ctfdsetup.Config) {
+				// Values remain strings, unless the field is not one
+				assert.Equal(t, "true", conf.Appearance.Name)
+				assert.Equal(t, "4", conf.Appearance.Description)
+				require.NotNil(t, conf.Security.RegistrationCode)
+				assert.Equal(t, "null", conf.Security.RegistrationCode.Content)
+				require.NotNil(t, conf.Settings.Paused)
+				assert.True(t, *conf.Settings.Paused)
+				assert.Equal(t, 4, *conf.Accounts.TeamSize)
+			},
+		},
+		"escape": {
+			Content: `pages:
+  additional:
+    - title: Index
+      route: index
+      content: |
+        <p>Costs $$5</p>
+        <script>document.title = ` + "`" + `$${ctf.name}` + "`" + `</script>
+admin:
+  name: admin
+  email: admin@example.com
+  password: pa$$$$w0rd
+`,
+			Check: func(t *testing.T, conf *ctfdsetup.Config) {
+				// Each $ of inline contents is escaped as $$
+				require.Len(t, conf.Pages.Additional, 1)
+				assert.Equal(t, "<p>Costs $5</p>\n<script>document.title = `${ctf.name}`</script>\n", string(conf.Pages.Additional[0].Content.Content))
+				assert.Equal(t, "pa$$w0rd", conf.Admin.Password.Content)
+			},
+		},
+		"template-literal": {
+			Content:   "pages:\n  additional:\n    - title: Index\n      route: index\n      content: \"<script>`${ctf.name}`</script>\"\n",
+			ExpectErr: true,
+		},
+		"quoted-non-string": {
+			Content:   "accounts:\n  team_size: \"${TEAM_SIZE}\"\n",
+			ExpectErr: true,
+		},
+		"unknown-field": {
+			Content:   "unknown: ${CTF_NAME}\n",
+			ExpectErr: true,
+		},
+		"invalid-name": {
+			Content:   "appearance:\n  name: ${CTF-NAME}\n",
+			ExpectErr: true,
+		},
+		"unterminated": {
+			Content:   "appearance:\n  name: ${CTF_NAME\n",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{".ctfd.yaml": tt.Content})
+
+			conf := ctfdsetup.NewConfig()
+			err := ctfdsetup.LoadConfig(filepath.Join(dir, ".ctfd.yaml"), "", conf)
+
+			if tt.ExpectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tt.Check(t, conf)
+		})
+	}
+}
+
+func Test_U_LoadConfigMissing(t *testing.T) {
+	t.Setenv("EMPTY", "")
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{".ctfd.yaml": `appearance:
+  name: ${CTF_MISSING_NAME:?}
+  description: ${EMPTY:?}
+admin:
+  name: admin
+  email: admin@example.com
+  password:
+    from_env: CTF_MISSING_PASSWORD
+`})
+	err := ctfdsetup.LoadConfig(filepath.Join(dir, ".ctfd.yaml"), "", ctfdsetup.NewConfig())
+
+	// All missing variables are reported at once
+	require.Error(t, err)
+	f := filepath.Join(dir, ".ctfd.yaml")
+	assert.Equal(t, "missing environment variables: CTF_MISSING_NAME ("+f+":2), EMPTY ("+f+":3), CTF_MISSING_PASSWORD ("+f+":8)", err.Error())
+}
+
+func Test_U_SchemaInterpolation(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ctfdsetup.Config{}.Schema()
+	require.NoError(t, err)
+
+	// Non-string fields accept interpolated values, as editors see them
+	doc := map[string]any{
+		"appearance": map[string]any{"name": "My CTF", "description": "${DESCRIPTION}"},
+		"admin": map[string]any{
+			"name":     "admin",
+			"email":    "admin@example.com",
+			"password": "${ADMIN_PASSWORD}",
+		},
+		"accounts": map[string]any{"team_size": "${TEAM_SIZE:-4}"},
+		"settings": map[string]any{"paused": "${PAUSED}"},
+	}
+	res, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewGoLoader(doc))
+	require.NoError(t, err)
+	assert.True(t, res.Valid(), "%v", res.Errors())
+
+	doc["accounts"] = map[string]any{"team_size": "four"}
+	res, err = gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewGoLoader(doc))
+	require.NoError(t, err)
+	assert.False(t, res.Valid())
+}
@@ -16,14 +16,17 @@ type Logger struct {
 }
 
 func (log *Logger) Info(_ context.Context, msg string, fields ...zap.Field) {
+	msg, fields = redact(msg, fields)
 	log.sub.Info(msg, fields...)
 }
 
 func (log *Logger) Debug(_ context.Context, msg string, fields ...zap.Field) {
+	msg, fields = redact(msg, fields)
 	log.sub.Debug(msg, fields...)
 }
 
 func (log *Logger) Error(_ context.Context, msg string, fields ...zap.Field) {
+	msg, fields = redact(msg, fields)
 	log.sub.Error(msg, fields...)
 }
 
@@ -0,0 +1,115 @@
+package ctfdsetup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// notificationsState is the state key of the notifications already sent by ctfd-setup.
+const notificationsState = "notifications"
+
+// validateNotifications checks the notifications keys are unique.
+func validateNotifications(notifs []*Notification) error {
+	var merr error
+	keys := map[string]struct{}{}
+	for _, notif := range notifs {
+		key := notif.key()
+		if _, ok := keys[key]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("notification %s: duplicated key", key))
+		}
+		keys[key] = struct{}{}
+	}
+	return merr
+}
+
+// key returns the stable key identifying the notification, defaulting to its title.
+func (notif *Notification) key() string {
+	if notif.Key != "" {
+		return notif.Key
+	}
+	return notif.Title
+}
+
+// sendNotifications sends the notifications that are due and were never sent.
+// Sent notifications are remembered, such that players are notified only once
+// even if the notification is deleted from CTFd.
+func sendNotifications(ctx context.Context, client *Client, notifs []*Notification, current map[string]string, opts ...Option) error {
+	if len(notifs) == 0 {
+		return nil
+	}
+
+	sent := []string{}
+	if err := readState(current, notificationsState, &sent); err != nil {
+		return err
+	}
+	manifest := slices.Clone(sent)
+
+	var merr error
+	for _, notif := range dueNotifications(notifs, sent, time.Now()) {
+		Log().Info(ctx, "sending notification",
+			zap.String("key", notif.key()),
+		)
+		if _, err := client.PostNotifications(ctx, &api.PostNotificationsParams{
+			Title:   notif.Title,
+			Content: string(deref(notif.Content).Content),
+			Type:    notificationType(notif),
+			Sound:   notif.Sound,
+		}, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "sending notification %s", notif.key()))
+			continue
+		}
+		manifest = append(manifest, notif.key())
+	}
+
+	// Save the manifest if it changed, even on partial failure to never send twice
+	if len(manifest) != len(sent) {
+		if err := writeState(ctx, client, notificationsState, manifest, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrap(err, "saving notifications manifest"))
+		}
+	}
+	return merr
+}
+
+// diffNotifications returns the notifications that would be sent now.
+func diffNotifications(notifs []*Notification, current map[string]string) ([]*Diff, error) {
+	sent := []string{}
+	if err := readState(current, notificationsState, &sent); err != nil {
+		return nil, err
+	}
+	diffs := []*Diff{}
+	for _, notif := range dueNotifications(notifs, sent, time.Now()) {
+		diffs = append(diffs, &Diff{
+			Kind:    DiffAdded,
+			Key:     "notifications." + notif.key(),
+			Desired: notif.Title,
+		})
+	}
+	return diffs, nil
+}
+
+// dueNotifications returns the notifications that were never sent, and that
+// are not held back until after now.
+func dueNotifications(notifs []*Notification, sent []string, now time.Time) []*Notification {
+	due := []*Notification{}
+	for _, notif := range notifs {
+		if slices.Contains(sent, notif.key()) {
+			continue
+		}
+		if notif.At != nil && now.Before(*notif.At) {
+			continue
+		}
+		due = append(due, notif)
+	}
+	return due
+}
+
+func notificationType(notif *Notification) string {
+	return orDefault(notif.Type, "toast")
+}
@@ -0,0 +1,61 @@
+package ctfdsetup_test
+
+import (
+	"testing"
+	"time"
+
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_U_DueNotifications(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 25, 10, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	var tests = map[string]struct {
+		Notifications []*ctfdsetup.Notification
+		Sent          []string
+		ExpectedDue   []string
+	}{
+		"never-sent": {
+			Notifications: []*ctfdsetup.Notification{
+				{Title: "Welcome"},
+			},
+			Sent:        []string{},
+			ExpectedDue: []string{"Welcome"},
+		},
+		"already-sent": {
+			Notifications: []*ctfdsetup.Notification{
+				{Title: "Welcome"},
+				{Key: "rules", Title: "Rules reminder"},
+			},
+			Sent:        []string{"Welcome", "rules"},
+			ExpectedDue: []string{},
+		},
+		"held-back": {
+			Notifications: []*ctfdsetup.Notification{
+				{Title: "Freeze in 1 hour", At: &before},
+				{Title: "Freeze", At: &after},
+			},
+			Sent:        []string{},
+			ExpectedDue: []string{"Freeze in 1 hour"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			due := ctfdsetup.DueNotifications(tt.Notifications, tt.Sent, now)
+
+			titles := []string{}
+			for _, notif := range due {
+				titles = append(titles, notif.Title)
+			}
+			assert.Equal(t, tt.ExpectedDue, titles)
+		})
+	}
+}
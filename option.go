@@ -1,6 +1,8 @@
 package ctfdsetup
 
 import (
+	"time"
+
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -10,7 +12,9 @@ type Option interface {
 }
 
 type options struct {
-	tracer trace.TracerProvider
+	tracer    trace.TracerProvider
+	readiness *readiness
+	retry     *retryPolicy
 }
 
 type tracerOption struct {
@@ -27,14 +31,75 @@ func WithTracerProvider(tracer trace.TracerProvider) Option {
 	}
 }
 
-func getTracer(opts ...Option) trace.Tracer {
+type readiness struct {
+	timeout, backoff time.Duration
+}
+
+type readinessOption struct {
+	readiness *readiness
+}
+
+func (opt readinessOption) apply(opts *options) {
+	opts.readiness = opt.readiness
+}
+
+// WithReadiness waits for the CTFd instance to be ready before reaching it,
+// polling it until the timeout expires.
+// The backoff is the delay between the first two attempts, then doubles
+// for each attempt.
+func WithReadiness(timeout, backoff time.Duration) Option {
+	return &readinessOption{
+		readiness: &readiness{
+			timeout: timeout,
+			backoff: backoff,
+		},
+	}
+}
+
+// maxRetryBackoff caps the delay between two attempts of an API call.
+const maxRetryBackoff = 30 * time.Second
+
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+}
+
+type retryOption struct {
+	retry *retryPolicy
+}
+
+func (opt retryOption) apply(opts *options) {
+	opts.retry = opt.retry
+}
+
+// WithRetry retries the API calls that failed due to a transient error,
+// i.e. a HTTP 5xx or 429 response to an idempotent request, up to the
+// given number of attempts.
+// The backoff is the delay between the first two attempts, then doubles
+// for each attempt, with jitter. A non-positive backoff retries without delay.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return &retryOption{
+		retry: &retryPolicy{
+			attempts: attempts,
+			backoff:  backoff,
+		},
+	}
+}
+
+func getOptions(opts ...Option) *options {
 	o := &options{
-		tracer: nil,
+		tracer:    nil,
+		readiness: nil,
+		retry:     nil,
 	}
 	for _, opt := range opts {
 		opt.apply(o)
 	}
+	return o
+}
 
+func getTracer(opts ...Option) trace.Tracer {
+	o := getOptions(opts...)
 	if o.tracer == nil {
 		o.tracer = otel.GetTracerProvider()
 	}
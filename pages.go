@@ -2,19 +2,50 @@ package ctfdsetup
 
 import (
 	"context"
+	"fmt"
 	"slices"
 
 	"github.com/ctfer-io/go-ctfd/api"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
 )
 
-func additionalPages(ctx context.Context, client *Client, pages []Page, opts ...Option) error {
+const (
+	// PagesPruneManaged deletes only the pages created by ctfd-setup that are
+	// not configured anymore.
+	PagesPruneManaged = "managed"
+	// PagesPruneAll deletes all the pages that are not configured, and
+	// overwrites the ones at a configured route.
+	PagesPruneAll = "all"
+	// PagesPruneNone never deletes pages.
+	PagesPruneNone = "none"
+
+	// pagesState is the state key of the pages IDs managed by ctfd-setup.
+	pagesState = "pages"
+)
+
+func additionalPages(ctx context.Context, client *Client, pages *Pages, current map[string]string, opts ...Option) error {
+	managed := []int{}
+	if err := readState(current, pagesState, &managed); err != nil {
+		return err
+	}
+
 	ctfdPages, err := client.GetPages(ctx, nil, opts...)
 	if err != nil {
 		return err
 	}
+
+	// Forget the managed pages that were deleted by other means
+	manifest := slices.DeleteFunc(slices.Clone(managed), func(id int) bool {
+		return !slices.ContainsFunc(ctfdPages, func(p *api.Page) bool {
+			return p.ID == id
+		})
+	})
+	manifest = append(manifest, legacyPages(current, pages, ctfdPages)...)
 	cu := []string{}
 
-	for _, page := range pages {
+	var merr error
+	for _, page := range pages.Additional {
 		var ctfdP *api.Page
 		for _, p := range ctfdPages {
 			if p.Route == page.Route {
@@ -25,6 +56,16 @@ func additionalPages(ctx context.Context, client *Client, pages []Page, opts ...
 
 		cu = append(cu, page.Route)
 		if ctfdP != nil {
+			// CONFLICT
+			if !ownsPage(pages.Prune, manifest, ctfdP) {
+				Log().Error(ctx, "page is not managed by ctfd-setup, skipping it",
+					zap.String("route", page.Route),
+					zap.Int("id", ctfdP.ID),
+				)
+				merr = multierr.Append(merr, fmt.Errorf("page %s (id %d) conflicts with an unmanaged page", page.Route, ctfdP.ID))
+				continue
+			}
+
 			// UPDATE
 			if _, err := client.PatchPage(ctx, ctfdP.ID, &api.PatchPageParams{
 				Title:        page.Title,
@@ -37,9 +78,12 @@ func additionalPages(ctx context.Context, client *Client, pages []Page, opts ...
 			}, opts...); err != nil {
 				return err
 			}
+			if !slices.Contains(manifest, ctfdP.ID) {
+				manifest = append(manifest, ctfdP.ID)
+			}
 		} else {
 			// CREATE
-			if _, err := client.PostPages(ctx, &api.PostPagesParams{
+			p, err := client.PostPages(ctx, &api.PostPagesParams{
 				Title:        page.Title,
 				Route:        page.Route,
 				Format:       page.Format,
@@ -47,19 +91,97 @@ func additionalPages(ctx context.Context, client *Client, pages []Page, opts ...
 				Draft:        page.Draft,
 				Hidden:       page.Hidden,
 				AuthRequired: page.AuthRequired,
-			}, opts...); err != nil {
+			}, opts...)
+			if err != nil {
 				return err
 			}
+			manifest = append(manifest, p.ID)
 		}
 	}
 
 	// DELETE
+	for _, ctfdP := range prunedPages(pages.Prune, manifest, ctfdPages, cu) {
+		Log().Info(ctx, "deleting page",
+			zap.String("route", ctfdP.Route),
+			zap.Int("id", ctfdP.ID),
+		)
+		if err := client.DeletePage(ctx, ctfdP.ID, opts...); err != nil {
+			return err
+		}
+		manifest = slices.DeleteFunc(manifest, func(id int) bool {
+			return id == ctfdP.ID
+		})
+	}
+
+	// Save the manifest if it changed, or the first time even if empty such that
+	// pages created by other means later on are never adopted as legacy ones
+	slices.Sort(manifest)
+	slices.Sort(managed)
+	if !slices.Equal(manifest, managed) || !hasState(current, pagesState) {
+		if err := writeState(ctx, client, pagesState, manifest, opts...); err != nil {
+			return multierr.Append(merr, err)
+		}
+	}
+	return merr
+}
+
+// adoptPages marks the existing pages at a configured route as managed by ctfd-setup.
+// It is used after a bare setup, as the pages CTFd creates (e.g. the index)
+// result from the setup itself.
+// The manifest is written even if empty, such that no page is adopted as a legacy one later on.
+func adoptPages(ctx context.Context, client *Client, pages *Pages, opts ...Option) error {
+	ids := []int{}
+	if pages == nil || len(pages.Additional) == 0 {
+		return writeState(ctx, client, pagesState, ids, opts...)
+	}
+	ctfdPages, err := client.GetPages(ctx, nil, opts...)
+	if err != nil {
+		return &ErrClient{err: err}
+	}
+	for _, p := range ctfdPages {
+		if slices.ContainsFunc(pages.Additional, func(page Page) bool {
+			return page.Route == p.Route
+		}) {
+			ids = append(ids, p.ID)
+		}
+	}
+	return writeState(ctx, client, pagesState, ids, opts...)
+}
+
+// legacyPages returns the IDs of the CTFd pages at a configured route if no pages
+// manifest exists yet. Versions of ctfd-setup that did not keep track of the pages
+// overwrote them, thus they are adopted on upgrade.
+func legacyPages(current map[string]string, pages *Pages, ctfdPages []*api.Page) []int {
+	ids := []int{}
+	if hasState(current, pagesState) {
+		return ids
+	}
+	for _, p := range ctfdPages {
+		if slices.ContainsFunc(pages.Additional, func(page Page) bool {
+			return page.Route == p.Route
+		}) {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+// ownsPage returns whether ctfd-setup can modify a CTFd page, given the prune policy.
+func ownsPage(prune string, managed []int, page *api.Page) bool {
+	return prune == PagesPruneAll || slices.Contains(managed, page.ID)
+}
+
+// prunedPages returns the CTFd pages to delete as they are not configured anymore,
+// given the prune policy.
+func prunedPages(prune string, managed []int, ctfdPages []*api.Page, routes []string) []*api.Page {
+	pruned := []*api.Page{}
+	if prune == PagesPruneNone {
+		return pruned
+	}
 	for _, ctfdP := range ctfdPages {
-		if !slices.Contains(cu, ctfdP.Route) {
-			if err := client.DeletePage(ctx, ctfdP.ID, opts...); err != nil {
-				return err
-			}
+		if !slices.Contains(routes, ctfdP.Route) && ownsPage(prune, managed, ctfdP) {
+			pruned = append(pruned, ctfdP)
 		}
 	}
-	return nil
+	return pruned
 }
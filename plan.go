@@ -0,0 +1,489 @@
+package ctfdsetup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ctfer-io/go-ctfd/api"
+)
+
+// DiffKind is the nature of a difference between a CTFd instance and a configuration.
+type DiffKind string
+
+const (
+	// DiffAdded means the configuration defines something the CTFd instance does not have.
+	DiffAdded DiffKind = "added"
+	// DiffChanged means the CTFd instance and the configuration disagree.
+	DiffChanged DiffKind = "changed"
+	// DiffRemoved means the CTFd instance has something the configuration removes.
+	DiffRemoved DiffKind = "removed"
+)
+
+// Diff is a field-level difference between a CTFd instance and a configuration.
+type Diff struct {
+	Kind DiffKind
+
+	// Key identifies the field, either a CTFd config key (e.g. "ctf_name") or
+	// a resource attribute (e.g. "pages.index.title").
+	Key string
+
+	// Current value on the CTFd instance, empty if added.
+	Current string
+
+	// Desired value from the configuration, empty if removed.
+	Desired string
+}
+
+func (d Diff) String() string {
+	switch d.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s: %q", d.Key, d.Desired)
+	case DiffChanged:
+		return fmt.Sprintf("~ %s: %q -> %q", d.Key, d.Current, d.Desired)
+	default:
+		return fmt.Sprintf("- %s: %q", d.Key, d.Current)
+	}
+}
+
+// sensitiveKeys are the CTFd config keys whose values must not be displayed.
+var sensitiveKeys = []string{
+	"mail_password",
+	"oauth_client_secret",
+	"registration_code",
+}
+
+// Plan compares the CTFd instance to the configuration and returns the
+// field-level differences that Setup would apply. It writes nothing.
+func Plan(ctx context.Context, url, apiKey string, conf *Config, opts ...Option) ([]*Diff, error) {
+	ctx, span := getTracer(opts...).Start(ctx, "Plan")
+	defer span.End()
+
+	client, b, err := connect(ctx, url, apiKey, conf, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return plan(ctx, client, b, conf, opts...)
+}
+
+// plan computes the differences between the CTFd instance and the configuration.
+// If the instance is bare, nothing can be read thus everything is considered added.
+func plan(ctx context.Context, client *Client, bare bool, conf *Config, opts ...Option) ([]*Diff, error) {
+	// Configs
+	current := map[string]string{}
+	if !bare {
+		var err error
+		current, err = getConfigs(ctx, client, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	desired, err := configValues(conf)
+	if err != nil {
+		return nil, err
+	}
+	diffs := diffConfigs(desired, current)
+
+	// Theme images
+	for key, file := range map[string]*File{
+		"ctf_logo":       conf.Theme.Logo,
+		"ctf_small_icon": conf.Theme.SmallIcon,
+	} {
+		d, err := diffThemeImage(ctx, client, key, current[key], file, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			diffs = append(diffs, d)
+		}
+	}
+
+	// Additional pages
+	if conf.Pages != nil {
+		pds, err := diffPages(ctx, client, bare, conf.Pages, current, opts...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, pds...)
+	}
+
+	// Uploads
+	for _, up := range conf.Uploads {
+		d, err := diffUpload(ctx, client, bare, up, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			diffs = append(diffs, d)
+		}
+	}
+
+	// Pruned uploads
+	if conf.UploadsPolicy == UploadsPrune {
+		managed := []string{}
+		if err := readState(current, uploadsState, &managed); err != nil {
+			return nil, err
+		}
+		for _, loc := range prunedUploads(managed, conf.Uploads) {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffRemoved,
+				Key:     "uploads." + loc,
+				Current: loc,
+			})
+		}
+	}
+
+	// Brackets
+	if conf.Brackets != nil {
+		bds, err := diffBrackets(ctx, client, bare, conf.Brackets, conf.Mode, current, opts...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, bds...)
+	}
+
+	// Custom fields
+	if conf.Fields != nil {
+		fds, err := diffFields(ctx, client, bare, conf.Fields, current, opts...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, fds...)
+	}
+
+	// Staff
+	if conf.Staff != nil {
+		sds, err := diffStaff(ctx, client, bare, conf.Staff, current, opts...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, sds...)
+	}
+
+	// Users
+	if conf.Users != nil {
+		uds, err := diffUsers(ctx, client, bare, conf.Users, opts...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, uds...)
+	}
+
+	// Teams
+	if conf.Teams != nil {
+		tds, err := diffTeams(ctx, client, bare, conf.Teams, opts...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, tds...)
+	}
+
+	// Challenges
+	if conf.Challenges != nil {
+		cds, err := diffChallenges(ctx, client, bare, conf.Challenges, current, opts...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, cds...)
+	}
+
+	// Administrator credentials rotation
+	ads, err := diffAdmin(ctx, client, bare, conf.Admin, opts...)
+	if err != nil {
+		return nil, err
+	}
+	diffs = append(diffs, ads...)
+
+	// Notifications
+	nds, err := diffNotifications(conf.Notifications, current)
+	if err != nil {
+		return nil, err
+	}
+	diffs = append(diffs, nds...)
+
+	// Decrypted values must never be displayed
+	hideDecrypted(diffs)
+
+	slices.SortStableFunc(diffs, func(a, b *Diff) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return diffs, nil
+}
+
+// getConfigs returns the CTFd configs as a key/value map.
+func getConfigs(ctx context.Context, client *Client, opts ...Option) (map[string]string, error) {
+	cfgs, err := client.GetConfigs(ctx, nil, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	current := make(map[string]string, len(cfgs))
+	for _, cfg := range cfgs {
+		current[cfg.Key] = cfg.Value
+	}
+	return current, nil
+}
+
+// misnamedConfigs maps the go-ctfd configs attributes that are not named after
+// their CTFd key to the actual one.
+var misnamedConfigs = map[string]string{
+	"domaine_blacklist": "domain_blacklist",
+}
+
+// configValues flattens the configs attributes to their CTFd keys, as they
+// are sent to CTFd.
+func configValues(conf *Config) (map[string]any, error) {
+	b, err := json.Marshal(configParams(conf))
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	values := map[string]any{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+	for from, to := range misnamedConfigs {
+		if v, ok := values[from]; ok {
+			delete(values, from)
+			values[to] = v
+		}
+	}
+	return values, nil
+}
+
+// diffConfigs compares the desired configs values to the current ones.
+// Configs not defined by the configuration are not compared, as Setup
+// does not touch them.
+func diffConfigs(desired map[string]any, current map[string]string) []*Diff {
+	diffs := []*Diff{}
+	for key, value := range desired {
+		cur, ok := current[key]
+		if sameValue(value, cur) {
+			continue
+		}
+
+		d := &Diff{
+			Kind:    DiffAdded,
+			Key:     key,
+			Current: cur,
+			Desired: formatValue(value),
+		}
+		if ok {
+			d.Kind = DiffChanged
+		}
+		if slices.Contains(sensitiveKeys, key) {
+			d.Current = hideValue(d.Current)
+			d.Desired = hideValue(d.Desired)
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// sameValue compares a value as sent to CTFd to the one it stores.
+// CTFd stores all configs as strings, thus booleans may be stored as
+// "1"/"0" or "true"/"false" depending on the database.
+func sameValue(desired any, current string) bool {
+	switch v := desired.(type) {
+	case bool:
+		b, _ := strconv.ParseBool(current)
+		return b == v
+	case nil:
+		return current == ""
+	default:
+		return formatValue(desired) == current
+	}
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func hideValue(v string) string {
+	if v == "" {
+		return ""
+	}
+	return "(sensitive)"
+}
+
+// diffThemeImage compares a theme image (logo, small icon) at the given location
+// to the configured file, based on their SHA1 sums.
+func diffThemeImage(ctx context.Context, client *Client, key, location string, file *File, opts ...Option) (*Diff, error) {
+	desired := file != nil && file.Name != ""
+	if location == "" {
+		if !desired {
+			return nil, nil
+		}
+		return &Diff{
+			Kind:    DiffAdded,
+			Key:     key,
+			Desired: file.Name,
+		}, nil
+	}
+	if !desired {
+		return &Diff{
+			Kind:    DiffRemoved,
+			Key:     key,
+			Current: location,
+		}, nil
+	}
+
+	fs, err := client.GetFiles(ctx, &api.GetFilesParams{
+		Location: &location,
+	}, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	if len(fs) != 0 && fs[0].SHA1sum == sha1sum(file.Content) {
+		return nil, nil
+	}
+	return &Diff{
+		Kind:    DiffChanged,
+		Key:     key,
+		Current: location,
+		Desired: file.Name,
+	}, nil
+}
+
+// diffPages compares the CTFd pages to the configured ones, per field.
+// Pages that ctfd-setup does not own are reported as conflicting, as
+// Setup would not overwrite them.
+func diffPages(ctx context.Context, client *Client, bare bool, pages *Pages, current map[string]string, opts ...Option) ([]*Diff, error) {
+	ctfdPages := []*api.Page{}
+	managed := []int{}
+	if !bare {
+		var err error
+		ctfdPages, err = client.GetPages(ctx, nil, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		if err := readState(current, pagesState, &managed); err != nil {
+			return nil, err
+		}
+		managed = append(managed, legacyPages(current, pages, ctfdPages)...)
+	}
+
+	diffs := []*Diff{}
+	routes := []string{}
+	for _, page := range pages.Additional {
+		routes = append(routes, page.Route)
+		key := "pages." + page.Route
+
+		idx := slices.IndexFunc(ctfdPages, func(p *api.Page) bool {
+			return p.Route == page.Route
+		})
+		if idx == -1 {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffAdded,
+				Key:     key,
+				Desired: page.Title,
+			})
+			continue
+		}
+		if !ownsPage(pages.Prune, managed, ctfdPages[idx]) {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffChanged,
+				Key:     key,
+				Current: "(unmanaged) " + ctfdPages[idx].Title,
+				Desired: page.Title,
+			})
+			continue
+		}
+
+		// Content is not listed, thus get the page itself
+		ctfdP, err := client.GetPage(ctx, ctfdPages[idx].ID, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		content := ""
+		if ctfdP.Content != nil {
+			content = *ctfdP.Content
+		}
+		format := page.Format
+		if format == "" {
+			format = "markdown" // CTFd default
+		}
+
+		for _, f := range []struct {
+			name             string
+			current, desired string
+		}{
+			{"title", ctfdP.Title, page.Title},
+			{"format", ctfdP.Format, format},
+			{"content", "sha1:" + sha1sum([]byte(content)), "sha1:" + sha1sum(page.Content.Content)},
+			{"draft", strconv.FormatBool(ctfdP.Draft), strconv.FormatBool(page.Draft)},
+			{"hidden", strconv.FormatBool(ctfdP.Hidden), strconv.FormatBool(page.Hidden)},
+			{"auth_required", strconv.FormatBool(ctfdP.AuthRequired), strconv.FormatBool(page.AuthRequired)},
+		} {
+			if f.current == f.desired {
+				continue
+			}
+			diffs = append(diffs, &Diff{
+				Kind:    DiffChanged,
+				Key:     key + "." + f.name,
+				Current: f.current,
+				Desired: f.desired,
+			})
+		}
+	}
+
+	// Pages that are not configured are deleted, given the prune policy
+	for _, ctfdP := range prunedPages(pages.Prune, managed, ctfdPages, routes) {
+		diffs = append(diffs, &Diff{
+			Kind:    DiffRemoved,
+			Key:     "pages." + ctfdP.Route,
+			Current: ctfdP.Title,
+		})
+	}
+	return diffs, nil
+}
+
+// diffUpload compares an upload to the file at its location, based on their SHA1 sums.
+func diffUpload(ctx context.Context, client *Client, bare bool, up *Upload, opts ...Option) (*Diff, error) {
+	key := "uploads." + up.Location
+	x := sha1sum(up.File.Content)
+	if bare {
+		return &Diff{
+			Kind:    DiffAdded,
+			Key:     key,
+			Desired: "sha1:" + x,
+		}, nil
+	}
+
+	fs, err := client.GetFiles(ctx, &api.GetFilesParams{
+		Location: &up.Location,
+	}, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	if len(fs) == 0 {
+		return &Diff{
+			Kind:    DiffAdded,
+			Key:     key,
+			Desired: "sha1:" + x,
+		}, nil
+	}
+	if fs[0].SHA1sum == x {
+		return nil, nil
+	}
+	return &Diff{
+		Kind:    DiffChanged,
+		Key:     key,
+		Current: "sha1:" + fs[0].SHA1sum,
+		Desired: "sha1:" + x,
+	}, nil
+}
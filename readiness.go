@@ -0,0 +1,49 @@
+package ctfdsetup
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// minReadinessBackoff is the lowest delay between two readiness attempts,
+	// such that the instance is not polled without any delay.
+	minReadinessBackoff = 100 * time.Millisecond
+	// maxReadinessBackoff caps the delay between two readiness attempts.
+	maxReadinessBackoff = 30 * time.Second
+)
+
+// WaitReady polls the CTFd instance until it serves the setup or login page
+// with a valid nonce, or the timeout expires.
+// It returns the nonce and session of the last attempt, ready to use.
+func WaitReady(ctx context.Context, url string, timeout, backoff time.Duration, opts ...Option) (nonce, session string, err error) {
+	ctx, span := getTracer(opts...).Start(ctx, "WaitReady")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff = max(backoff, minReadinessBackoff)
+
+	for attempt := 1; ; attempt++ {
+		nonce, session, err = GetNonceAndSession(ctx, url, opts...)
+		if err == nil {
+			return nonce, session, nil
+		}
+
+		Log().Info(ctx, "CTFd is not ready yet",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return "", "", errors.Wrapf(err, "CTFd not ready after %s", timeout)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxReadinessBackoff)
+	}
+}
@@ -0,0 +1,77 @@
+package ctfdsetup_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_U_WaitReady(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	nonce := strings.Repeat("a", 64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempts, as CTFd would while starting
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "sess"})
+		_, _ = w.Write([]byte(`<script>var csrfNonce = "` + nonce + `";</script>`))
+	}))
+	defer srv.Close()
+
+	var tests = map[string]struct {
+		Timeout   time.Duration
+		ExpectErr bool
+	}{
+		"ready-in-time": {
+			Timeout:   5 * time.Second,
+			ExpectErr: false,
+		},
+		"timeout": {
+			Timeout:   time.Nanosecond,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			calls.Store(0)
+			assert := assert.New(t)
+
+			n, s, err := ctfdsetup.WaitReady(t.Context(), srv.URL, tt.Timeout, 10*time.Millisecond)
+			if tt.ExpectErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(nonce, n)
+			assert.Equal("sess", s)
+			assert.Equal(int32(3), calls.Load())
+		})
+	}
+}
+
+func Test_U_WaitReadyNoBackoff(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	// A zero backoff does not poll the instance without delay
+	_, _, err := ctfdsetup.WaitReady(t.Context(), srv.URL, 250*time.Millisecond, 0)
+	assert.Error(t, err)
+	assert.LessOrEqual(t, calls.Load(), int32(3))
+}
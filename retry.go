@@ -0,0 +1,150 @@
+package ctfdsetup
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// idempotentMethods are the HTTP methods that can safely be retried.
+// CTFd PATCH endpoints set the resources attributes thus are idempotent too.
+var idempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// attempt records the HTTP exchanges of an API call attempt, such that
+// retry can decide whether it is worth another try.
+type attempt struct {
+	status     int
+	idempotent bool
+	retryAfter time.Duration
+}
+
+type attemptKey struct{}
+
+// recordingTransport records the HTTP exchanges in the attempt carried
+// by the request context, if any.
+type recordingTransport struct {
+	next http.RoundTripper
+}
+
+var _ http.RoundTripper = (*recordingTransport)(nil)
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if a, ok := req.Context().Value(attemptKey{}).(*attempt); ok {
+		if !slices.Contains(idempotentMethods, req.Method) {
+			a.idempotent = false
+		}
+		if res != nil {
+			a.status = res.StatusCode
+			a.retryAfter = 0
+			if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+				a.retryAfter = retryAfter(res.Header.Get("Retry-After"))
+			}
+		}
+	}
+	return res, err
+}
+
+// retryAfter returns the delay a Retry-After header value asks for, either
+// in seconds or as an HTTP date. It returns 0 if the value is invalid.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(s)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// retryable returns whether the attempt failed due to a transient error.
+// Only calls made of idempotent requests are retryable.
+func (a *attempt) retryable() bool {
+	return a.idempotent && (a.status >= 500 || a.status == http.StatusTooManyRequests)
+}
+
+// retry calls f until it succeeds, fails due to a non-transient error, or
+// the retry policy is exhausted.
+// Each retry is recorded as an event of the span in context.
+func retry(ctx context.Context, opts []Option, f func(ctx context.Context) error) error {
+	r := getOptions(opts...).retry
+	if r == nil {
+		return f(ctx)
+	}
+
+	span := trace.SpanFromContext(ctx)
+	for n := 1; ; n++ {
+		a := &attempt{
+			idempotent: true,
+		}
+		err := f(context.WithValue(ctx, attemptKey{}, a))
+		if err == nil || n >= r.attempts || !a.retryable() {
+			return err
+		}
+
+		// Never retry earlier than the server allows, up to the maximum backoff
+		backoff := r.delay(n)
+		if a.retryAfter > backoff {
+			backoff = min(a.retryAfter, maxRetryBackoff)
+		}
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", n),
+			attribute.Int("http.response.status_code", a.status),
+			attribute.String("backoff", backoff.String()),
+			attribute.String("error", err.Error()),
+		))
+		Log().Debug(ctx, "retrying api call",
+			zap.Int("attempt", n),
+			zap.Int("status", a.status),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// retryValue is retry for calls that return a value.
+func retryValue[T any](ctx context.Context, opts []Option, f func(ctx context.Context) (T, error)) (T, error) {
+	var out T
+	err := retry(ctx, opts, func(ctx context.Context) (err error) {
+		out, err = f(ctx)
+		return
+	})
+	return out, err
+}
+
+// delay returns the backoff before the attempt n+1: exponential, with half of it
+// being random (jitter) to avoid all clients retrying at once.
+// A non-positive backoff retries without delay.
+func (r *retryPolicy) delay(n int) time.Duration {
+	if r.backoff <= 0 {
+		return 0
+	}
+	d := r.backoff << (n - 1)
+	// d is non-positive only when the shift overflowed
+	if d <= 0 || d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
@@ -0,0 +1,155 @@
+package ctfdsetup_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_U_Retry(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Status         int
+		Call           func(t *testing.T, cli *ctfdsetup.Client, opts ...ctfdsetup.Option) error
+		ExpectedCalls  int32
+		ExpectedEvents int
+		ExpectErr      bool
+	}{
+		"idempotent-5xx": {
+			Status: http.StatusBadGateway,
+			Call: func(t *testing.T, cli *ctfdsetup.Client, opts ...ctfdsetup.Option) error {
+				_, err := cli.GetConfigs(t.Context(), nil, opts...)
+				return err
+			},
+			ExpectedCalls:  3,
+			ExpectedEvents: 2,
+			ExpectErr:      false,
+		},
+		"idempotent-429": {
+			Status: http.StatusTooManyRequests,
+			Call: func(t *testing.T, cli *ctfdsetup.Client, opts ...ctfdsetup.Option) error {
+				return cli.PatchConfigsValues(t.Context(), map[string]any{"ctf_name": "x"}, opts...)
+			},
+			ExpectedCalls:  3,
+			ExpectedEvents: 2,
+			ExpectErr:      false,
+		},
+		"idempotent-4xx": {
+			Status: http.StatusBadRequest,
+			Call: func(t *testing.T, cli *ctfdsetup.Client, opts ...ctfdsetup.Option) error {
+				_, err := cli.GetConfigs(t.Context(), nil, opts...)
+				return err
+			},
+			ExpectedCalls:  1,
+			ExpectedEvents: 0,
+			ExpectErr:      true,
+		},
+		"non-idempotent": {
+			Status: http.StatusBadGateway,
+			Call: func(t *testing.T, cli *ctfdsetup.Client, opts ...ctfdsetup.Option) error {
+				_, err := cli.PostPages(t.Context(), &api.PostPagesParams{}, opts...)
+				return err
+			},
+			ExpectedCalls:  1,
+			ExpectedEvents: 0,
+			ExpectErr:      true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+			assert := assert.New(t)
+
+			// Fail the first 2 calls
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= 2 {
+					w.WriteHeader(tt.Status)
+					return
+				}
+				_, _ = w.Write([]byte(`{"success": true, "data": null}`))
+			}))
+			defer srv.Close()
+
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+			cli := ctfdsetup.NewClient(srv.URL, "nonce", "session", "")
+			err := tt.Call(t, cli,
+				ctfdsetup.WithTracerProvider(tp),
+				ctfdsetup.WithRetry(3, time.Millisecond),
+			)
+			if tt.ExpectErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tt.ExpectedCalls, calls.Load())
+
+			events := 0
+			for _, span := range sr.Ended() {
+				for _, e := range span.Events() {
+					if e.Name == "retry" {
+						events++
+					}
+				}
+			}
+			assert.Equal(tt.ExpectedEvents, events)
+		})
+	}
+}
+
+func Test_U_RetryAfter(t *testing.T) {
+	t.Parallel()
+
+	// Ask to retry after a second, once
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"success": true, "data": null}`))
+	}))
+	defer srv.Close()
+
+	cli := ctfdsetup.NewClient(srv.URL, "nonce", "session", "")
+	start := time.Now()
+	_, err := cli.GetConfigs(t.Context(), nil, ctfdsetup.WithRetry(3, time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func Test_U_RetryNoBackoff(t *testing.T) {
+	t.Parallel()
+
+	// Fail the first 2 calls
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"success": true, "data": null}`))
+	}))
+	defer srv.Close()
+
+	cli := ctfdsetup.NewClient(srv.URL, "nonce", "session", "")
+	start := time.Now()
+	_, err := cli.GetConfigs(t.Context(), nil, ctfdsetup.WithRetry(3, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Less(t, time.Since(start), time.Second)
+}
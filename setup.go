@@ -4,10 +4,15 @@ import (
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
 
 	"github.com/ctfer-io/go-ctfd/api"
 	"github.com/pkg/errors"
-	"go.uber.org/multierr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -15,33 +20,87 @@ func Setup(ctx context.Context, url, apiKey string, conf *Config, opts ...Option
 	ctx, span := getTracer(opts...).Start(ctx, "Setup")
 	defer span.End()
 
-	nonce, session, err := GetNonceAndSession(ctx, url, opts...)
+	client, b, err := connect(ctx, url, apiKey, conf, opts...)
 	if err != nil {
-		return errors.Wrap(err, "getting CTFd nonce and session")
+		return err
+	}
+	if b {
+		if err := bareSetup(ctx, client, conf, opts...); err != nil {
+			return err
+		}
+		if err := bootstrapToken(ctx, client, conf.Token, opts...); err != nil {
+			return errors.Wrap(err, "bootstrapping API token")
+		}
+		if err := adoptPages(ctx, client, conf.Pages, opts...); err != nil {
+			return errors.Wrap(err, "adopting pages")
+		}
+	}
+	if err := updateSetup(ctx, client, conf, opts...); err != nil {
+		return err
+	}
+
+	// Rotate the administrator credentials last, as the session may not survive it
+	if err := rotateAdmin(ctx, client, conf.Admin, opts...); err != nil {
+		return errors.Wrap(err, "rotating administrator credentials")
+	}
+	return nil
+}
+
+// connect reaches the CTFd instance (waiting for it to be ready if asked to) and
+// returns a client ready to use, along with whether the instance is bare (i.e. not
+// setup yet).
+// If the instance is not bare and no API key is provided, it reuses the token
+// bootstrapped by a previous run if any, else logs in using the administrator
+// credentials, or the rotated ones if they got rejected.
+func connect(ctx context.Context, url, apiKey string, conf *Config, opts ...Option) (*Client, bool, error) {
+	var nonce, session string
+	var err error
+	if r := getOptions(opts...).readiness; r != nil {
+		nonce, session, err = WaitReady(ctx, url, r.timeout, r.backoff, opts...)
+	} else {
+		nonce, session, err = GetNonceAndSession(ctx, url, opts...)
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "getting CTFd nonce and session")
 	}
 	client := NewClient(url, nonce, session, apiKey)
 
 	b, err := client.Bare(ctx, opts...)
 	if err != nil {
-		return err
+		return nil, false, err
+	}
+	if !b && apiKey == "" {
+		if apiKey, err = reuseToken(conf.Token); err != nil {
+			return nil, false, err
+		}
+		if apiKey != "" {
+			client = NewClient(url, nonce, session, apiKey)
+		}
 	}
 	Log().Info(ctx, "deciding on CTFd setup strategy",
 		zap.Bool("bare", b),
 		zap.Bool("login", apiKey == ""),
 	)
-	if b {
-		if err := bareSetup(ctx, client, conf, opts...); err != nil {
-			return err
+	if !b && apiKey == "" {
+		if rotatedPassword(conf.Admin) != conf.Admin.Password.Content {
+			// The credentials may have been rotated by a previous run
+			client, err = adminLogin(ctx, url, conf.Admin.Name.Content, conf.Admin.Password.Content, opts...)
+			if err != nil {
+				client, err = adminLogin(ctx, url, conf.Admin.Name.Content, rotatedPassword(conf.Admin), opts...)
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			return client, b, nil
 		}
-	} else if apiKey == "" {
 		if err := client.Login(ctx, &api.LoginParams{
 			Name:     conf.Admin.Name.Content,
 			Password: conf.Admin.Password.Content,
 		}, opts...); err != nil {
-			return &ErrClient{err: err}
+			return nil, false, &ErrClient{err: err}
 		}
 	}
-	return updateSetup(ctx, client, conf, opts...)
+	return client, b, nil
 }
 
 func bareSetup(ctx context.Context, client *Client, conf *Config, opts ...Option) error {
@@ -51,6 +110,7 @@ func bareSetup(ctx context.Context, client *Client, conf *Config, opts ...Option
 		CTFDescription:         conf.Appearance.Description,
 		UserMode:               conf.Mode,
 		CTFTheme:               conf.Theme.Name,
+		ThemeColor:             conf.Theme.Color,
 		ChallengeVisibility:    conf.Settings.ChallengeVisibility,
 		AccountVisibility:      conf.Settings.AccountVisibility,
 		ScoreVisibility:        conf.Settings.ScoreVisibility,
@@ -67,58 +127,200 @@ func bareSetup(ctx context.Context, client *Client, conf *Config, opts ...Option
 }
 
 func updateSetup(ctx context.Context, client *Client, conf *Config, opts ...Option) error {
+	current, err := getConfigs(ctx, client, opts...)
+	if err != nil {
+		return err
+	}
+
 	// Push logo
-	if conf.Theme.Logo.Name != "" {
-		lf, err := client.PostFiles(ctx, &api.PostFilesParams{
-			Files: []*api.InputFile{
-				(*api.InputFile)(conf.Theme.Logo),
-			},
-		}, opts...)
-		if err != nil {
-			return errors.Wrap(err, "pushing theme logo")
+	if err := updateThemeImage(ctx, client, current["ctf_logo"], conf.Theme.Logo, client.PatchConfigsCTFLogo, opts...); err != nil {
+		return errors.Wrap(err, "updating theme logo")
+	}
+
+	// Push small icon
+	if err := updateThemeImage(ctx, client, current["ctf_small_icon"], conf.Theme.SmallIcon, client.PatchConfigsCTFSmallIcon, opts...); err != nil {
+		return errors.Wrap(err, "updating theme small icon")
+	}
+
+	// Update configs attributes
+	if err := updateConfigs(ctx, client, conf, current, opts...); err != nil {
+		return err
+	}
+
+	// Handle additional pages configuration
+	if conf.Pages != nil {
+		if err := additionalPages(ctx, client, conf.Pages, current, opts...); err != nil {
+			return err
 		}
-		if _, err := client.PatchConfigsCTFLogo(ctx, &api.PatchConfigsCTFLogo{
-			Value: &lf[0].Location,
-		}, opts...); err != nil {
-			return errors.Wrap(err, "patching CTF logo")
+	}
+
+	// Upload files
+	if err := updateUploads(ctx, client, conf, current, opts...); err != nil {
+		return err
+	}
+
+	// Create and update brackets
+	if conf.Brackets != nil {
+		if err := updateBrackets(ctx, client, conf.Brackets, conf.Mode, current, opts...); err != nil {
+			return err
 		}
-	} else {
-		if _, err := client.PatchConfigsCTFLogo(ctx, &api.PatchConfigsCTFLogo{}, opts...); err != nil {
+	}
+
+	// Create and update custom fields
+	if conf.Fields != nil {
+		if err := updateFields(ctx, client, conf.Fields, current, opts...); err != nil {
 			return err
 		}
 	}
-	// TODO else delete logo
 
-	// Push small icon
-	if conf.Theme.SmallIcon.Name != "" {
-		smf, err := client.PostFiles(ctx, &api.PostFilesParams{
+	// Create and update staff accounts
+	if conf.Staff != nil {
+		if err := updateStaff(ctx, client, conf.Staff, current, opts...); err != nil {
+			return err
+		}
+	}
+
+	// Create and update users, before teams as they can be members
+	if conf.Users != nil {
+		if err := updateUsers(ctx, client, conf.Users, opts...); err != nil {
+			return err
+		}
+	}
+
+	// Create and update teams
+	if conf.Teams != nil {
+		if err := updateTeams(ctx, client, conf.Teams, opts...); err != nil {
+			return err
+		}
+	}
+
+	// Create and update challenges
+	if conf.Challenges != nil {
+		if err := updateChallenges(ctx, client, conf.Challenges, current, opts...); err != nil {
+			return err
+		}
+	}
+
+	// Send notifications, last as they may announce what got set up
+	if err := sendNotifications(ctx, client, conf.Notifications, current, opts...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateThemeImage makes the theme image (logo, small icon) at the given location
+// match the configured file, based on their SHA1 sums.
+// It is re-uploaded only if its content changed, and the previous file is deleted
+// once replaced or removed.
+func updateThemeImage(
+	ctx context.Context,
+	client *Client,
+	location string,
+	file *File,
+	patch func(context.Context, *api.PatchConfigsCTFLogo, ...Option) (*api.ThemeImage, error),
+	opts ...Option,
+) error {
+	desired := file != nil && file.Name != ""
+
+	// Get the current file from CTFd, if any
+	var old *api.File
+	if location != "" {
+		fs, err := client.GetFiles(ctx, &api.GetFilesParams{
+			Location: &location,
+		}, opts...)
+		if err != nil {
+			return errors.Wrapf(err, "getting file at %s", location)
+		}
+		if len(fs) != 0 {
+			old = fs[0]
+		}
+	}
+
+	// Check if need re-push
+	if desired && old != nil && old.SHA1sum == sha1sum(file.Content) {
+		return nil
+	}
+
+	if desired {
+		logger.Debug(ctx, "uploading theme image",
+			zap.String("name", file.Name),
+		)
+		fs, err := client.PostFiles(ctx, &api.PostFilesParams{
 			Files: []*api.InputFile{
-				(*api.InputFile)(conf.Theme.SmallIcon),
+				{Name: file.Name, Content: file.Content},
 			},
 		}, opts...)
 		if err != nil {
-			return errors.Wrap(err, "pushing theme small icon")
+			return errors.Wrap(err, "pushing file")
 		}
-		if _, err := client.PatchConfigsCTFSmallIcon(ctx, &api.PatchConfigsCTFLogo{
-			Value: &smf[0].Location,
+		if _, err := patch(ctx, &api.PatchConfigsCTFLogo{
+			Value: &fs[0].Location,
 		}, opts...); err != nil {
-			return errors.Wrap(err, "patching CTF small icon")
+			return errors.Wrap(err, "patching config")
 		}
-	} else {
-		if _, err := client.PatchConfigsCTFSmallIcon(ctx, &api.PatchConfigsCTFLogo{}, opts...); err != nil {
-			return err
+	} else if location != "" {
+		if _, err := patch(ctx, &api.PatchConfigsCTFLogo{}, opts...); err != nil {
+			return errors.Wrap(err, "patching config")
 		}
 	}
-	// TODO else delete small icon
 
-	// Update configs attributes
+	// Delete the replaced or removed file
+	if old != nil {
+		logger.Debug(ctx, "deleting theme image",
+			zap.String("location", old.Location),
+		)
+		if err := client.DeleteFile(ctx, old.ID, opts...); err != nil {
+			return errors.Wrapf(err, "deleting file at %s", old.Location)
+		}
+	}
+	return nil
+}
+
+// updateConfigs patches only the configs attributes that differ from the CTFd ones.
+// This avoids overwriting concurrent edits of the other keys, and makes no
+// write when nothing changed.
+func updateConfigs(ctx context.Context, client *Client, conf *Config, current map[string]string, opts ...Option) error {
+	desired, err := configValues(conf)
+	if err != nil {
+		return err
+	}
+
+	changed := map[string]any{}
+	for key, value := range desired {
+		if !sameValue(value, current[key]) {
+			changed[key] = value
+		}
+	}
+	keys := slices.Sorted(maps.Keys(changed))
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.StringSlice("ctfd.configs.changed", keys),
+	)
+	if len(changed) == 0 {
+		Log().Info(ctx, "configs are up to date")
+		return nil
+	}
+
+	Log().Info(ctx, "patching configs",
+		zap.Strings("keys", keys),
+	)
+	if err := client.PatchConfigsValues(ctx, changed, opts...); err != nil {
+		return &ErrClient{err: err}
+	}
+	return nil
+}
+
+// configParams maps the configuration to the CTFd configs attributes.
+// It is the single source of truth for what gets patched, and what
+// gets compared when planning.
+func configParams(conf *Config) *api.PatchConfigsParams {
 	params := &api.PatchConfigsParams{
 		CTFDescription:                     &conf.Appearance.Description,
 		CTFName:                            &conf.Appearance.Name,
 		DefaultLocale:                      conf.Appearance.DefaultLocale,
 		CTFTheme:                           &conf.Theme.Name,
 		ThemeFooter:                        ptr(string(conf.Theme.Footer.Content)),
-		ThemeHeader:                        ptr(string(conf.Theme.Header.Content)),
+		ThemeHeader:                        ptr(themeHeader(conf.Theme)),
 		ThemeSettings:                      ptr(string(conf.Theme.Settings.Content)),
 		DomainWhitelist:                    conf.Accounts.DomainWhitelist,
 		DomainBlacklist:                    conf.Accounts.DomainBlacklist,
@@ -138,18 +340,18 @@ func updateSetup(ctx context.Context, client *Client, conf *Config, opts ...Opti
 		ChallengeRatings:                   conf.Challenges.ChallengeRatings,
 		RobotsTxt:                          ptr(string(conf.Pages.RobotsTxt.Content)),
 		OauthClientID:                      conf.MajorLeagueCyber.ClientID,
-		OauthClientSecret:                  conf.MajorLeagueCyber.ClientSecret,
+		OauthClientSecret:                  conf.MajorLeagueCyber.ClientSecret.ptr(),
 		AccountVisibility:                  &conf.Settings.AccountVisibility,
 		ChallengeVisibility:                &conf.Settings.ChallengeVisibility,
 		RegistrationVisibility:             &conf.Settings.RegistrationVisibility,
 		ScoreVisibility:                    &conf.Settings.ScoreVisibility,
 		Paused:                             conf.Settings.Paused,
 		HTMLSanitization:                   conf.Security.HTMLSanitization,
-		RegistrationCode:                   conf.Security.RegistrationCode,
+		RegistrationCode:                   conf.Security.RegistrationCode.ptr(),
 		MailUseAuth:                        nil, // Handled later
 		MailUsername:                       nil, // Handled later
 		MailPassword:                       nil, // Handled later
-		MailFromAddr:                       nil, // Deprecated, set to nil for autocomplete
+		MailFromAddr:                       conf.Email.From,
 		MailGunAPIKey:                      nil, // Deprecated, set to nil for autocomplete
 		MailGunBaseURL:                     nil, // Deprecated, set to nil for autocomplete
 		MailPort:                           conf.Email.Port,
@@ -183,66 +385,45 @@ func updateSetup(ctx context.Context, client *Client, conf *Config, opts ...Opti
 	if conf.Email.Username != nil && conf.Email.Password != nil {
 		params.MailUseAuth = ptr(true)
 		params.MailUsername = conf.Email.Username
-		params.MailPassword = conf.Email.Password
-	}
-
-	if err := client.PatchConfigs(ctx, params, opts...); err != nil {
-		return &ErrClient{err: err}
-	}
-
-	// Handle additional pages configuration
-	if conf.Pages != nil && len(conf.Pages.Additional) != 0 {
-		if err := additionalPages(ctx, client, conf.Pages.Additional, opts...); err != nil {
-			return err
-		}
+		params.MailPassword = conf.Email.Password.ptr()
 	}
+	return params
+}
 
-	// Upload files
-	if len(conf.Uploads) != 0 {
-		var merr error
-		for _, f := range conf.Uploads {
-			// Compute file hash
-			h := sha1.New()
-			_, err := h.Write(f.File.Content)
-			if err != nil {
-				merr = multierr.Append(merr, errors.Wrapf(err, "computing hash of %s", f.File.Name))
-				continue
-			}
-			x := hex.EncodeToString(h.Sum(nil))
+// themeColorStyle is the style CTFd injects in the theme header to apply
+// the theme color, as it does on setup.
+const themeColorStyle = `<style id="theme-color">
+:root {--theme-color: %s;}
+.navbar{background-color: var(--theme-color) !important;}
+.jumbotron{background-color: var(--theme-color) !important;}
+</style>
+`
 
-			// Get the file from CTFd
-			fs, err := client.GetFiles(ctx, &api.GetFilesParams{
-				Location: &f.Location,
-			}, opts...)
-			if err != nil {
-				merr = multierr.Append(merr, errors.Wrapf(err, "getting file at %s", f.Location))
-				continue
-			}
+var themeColorRegex = regexp.MustCompile(`^<style id="theme-color">\n:root {--theme-color: ([^;]*);}\n[^<]*</style>\n?`)
 
-			// Check if need re-push
-			if len(fs) != 0 && fs[0].SHA1sum == x {
-				continue
-			}
+// themeHeader returns the theme header content, prefixed by the theme color style if any.
+func themeHeader(theme *Theme) string {
+	header := string(theme.Header.Content)
+	if theme.Color == "" {
+		return header
+	}
+	return fmt.Sprintf(themeColorStyle, theme.Color) + header
+}
 
-			// Else push it (or update it)
-			logger.Debug(ctx, "uploading file",
-				zap.String("location", f.Location),
-			)
-			if _, err := client.PostFiles(ctx, &api.PostFilesParams{
-				Files: []*api.InputFile{
-					(*api.InputFile)(f.File),
-				},
-				Location: &f.Location,
-			}, opts...); err != nil {
-				merr = multierr.Append(merr, err)
-			}
-		}
-		if merr != nil {
-			return merr
-		}
+// splitThemeHeader is the reverse of themeHeader: it returns the theme color and the
+// theme header content without its style.
+func splitThemeHeader(header string) (color, content string) {
+	m := themeColorRegex.FindStringSubmatchIndex(header)
+	if m == nil {
+		return "", header
 	}
+	return header[m[2]:m[3]], header[m[1]:]
+}
 
-	return nil
+// sha1sum returns the hex-encoded SHA1 sum of the content, as CTFd computes it.
+func sha1sum(content []byte) string {
+	h := sha1.Sum(content)
+	return hex.EncodeToString(h[:])
 }
 
 func ptr[T any](t T) *T {
@@ -0,0 +1,300 @@
+package ctfdsetup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// staffState is the state key of the staff accounts IDs managed by ctfd-setup.
+const staffState = "staff"
+
+// validateStaff checks the staff members names and emails are unique, they have
+// a password and none of them is the administrator nor a user.
+func validateStaff(staff *Staff, admin Admin, users *Users) error {
+	if staff == nil {
+		return nil
+	}
+
+	// Users that can't be loaded are reported by validateUsers
+	userEmails := map[string]struct{}{}
+	if users != nil {
+		if list, err := users.all(); err == nil {
+			for _, user := range list {
+				userEmails[strings.ToLower(user.Email)] = struct{}{}
+			}
+		}
+	}
+
+	var merr error
+	names := map[string]struct{}{}
+	emails := map[string]struct{}{}
+	for _, member := range staff.List {
+		if _, ok := names[member.Name]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("staff member %s: duplicated name", member.Name))
+		}
+		names[member.Name] = struct{}{}
+
+		email := strings.ToLower(member.Email)
+		if _, ok := emails[email]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("staff member %s: duplicated email", member.Name))
+		}
+		emails[email] = struct{}{}
+
+		if member.Password.Content == "" {
+			merr = multierr.Append(merr, fmt.Errorf("staff member %s: missing password", member.Name))
+		}
+		if member.Name == admin.Name.Content || strings.EqualFold(member.Email, admin.Email.Content) {
+			merr = multierr.Append(merr, fmt.Errorf("staff member %s: is the administrator", member.Name))
+		}
+		if _, ok := userEmails[email]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("staff member %s: is also a user", member.Name))
+		}
+	}
+	return merr
+}
+
+// updateStaff creates and updates the staff members as hidden administrators,
+// matched by email, then deletes the managed ones that were removed if asked to.
+// Passwords are only set on creation.
+// Accounts that ctfd-setup did not create are never promoted nor deleted, but
+// reported as conflicting.
+func updateStaff(ctx context.Context, client *Client, staff *Staff, current map[string]string, opts ...Option) error {
+	// Nothing to manage
+	if len(staff.List) == 0 && !staff.Prune {
+		return nil
+	}
+
+	managed := []int{}
+	if err := readState(current, staffState, &managed); err != nil {
+		return err
+	}
+
+	// Forget the managed accounts that were deleted by other means
+	ctfdStaff, err := managedStaff(ctx, client, managed, opts...)
+	if err != nil {
+		return err
+	}
+	manifest := []int{}
+	for _, ctfdU := range ctfdStaff {
+		manifest = append(manifest, ctfdU.ID)
+	}
+
+	var merr error
+	for _, member := range staff.List {
+		ctfdU, err := findUser(ctx, client, member.Email, opts...)
+		if err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "getting staff member %s", member.Name))
+			continue
+		}
+
+		if ctfdU == nil {
+			// CREATE
+			Log().Info(ctx, "creating staff member",
+				zap.String("name", member.Name),
+			)
+			ctfdU, err := client.PostUsers(ctx, &api.PostUsersParams{
+				Name:     member.Name,
+				Email:    member.Email,
+				Password: member.Password.Content,
+				Type:     "admin",
+				Verified: true,
+				Hidden:   true,
+				Fields:   []api.Field{},
+			}, opts...)
+			if err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "creating staff member %s", member.Name))
+				continue
+			}
+			manifest = append(manifest, ctfdU.ID)
+			continue
+		}
+
+		// CONFLICT
+		if !slices.Contains(manifest, ctfdU.ID) {
+			Log().Error(ctx, "staff member is not managed by ctfd-setup, skipping it",
+				zap.String("name", member.Name),
+				zap.Int("id", ctfdU.ID),
+			)
+			merr = multierr.Append(merr, fmt.Errorf("staff member %s conflicts with an unmanaged account (id %d)", member.Name, ctfdU.ID))
+			continue
+		}
+
+		// UPDATE
+		if len(diffStaffMember(ctfdU, member)) != 0 {
+			Log().Info(ctx, "updating staff member",
+				zap.String("name", member.Name),
+				zap.Int("id", ctfdU.ID),
+			)
+			if _, err := client.PatchUser(ctx, ctfdU.ID, &api.PatchUsersParams{
+				Name:     member.Name,
+				Email:    deref(ctfdU.Email), // keep the case it was registered with
+				Type:     ptr("admin"),
+				Verified: ptr(true),
+				Hidden:   ptr(true),
+				Fields:   []api.Field{},
+			}, opts...); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "updating staff member %s", member.Name))
+				continue
+			}
+		}
+	}
+
+	// DELETE
+	if staff.Prune {
+		for _, ctfdU := range prunedStaff(ctfdStaff, staff.List) {
+			Log().Info(ctx, "deleting staff member",
+				zap.String("name", ctfdU.Name),
+				zap.Int("id", ctfdU.ID),
+			)
+			if err := client.DeleteUser(ctx, ctfdU.ID, opts...); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "deleting staff member %s", ctfdU.Name))
+				continue
+			}
+			manifest = slices.DeleteFunc(manifest, func(id int) bool {
+				return id == ctfdU.ID
+			})
+		}
+	}
+
+	// Save the manifest if it changed
+	slices.Sort(manifest)
+	slices.Sort(managed)
+	if !slices.Equal(manifest, managed) {
+		if err := writeState(ctx, client, staffState, manifest, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrap(err, "saving staff manifest"))
+		}
+	}
+	return merr
+}
+
+// diffStaff compares the CTFd staff accounts to the configured ones, per field.
+// Passwords can't be read thus are not compared.
+func diffStaff(ctx context.Context, client *Client, bare bool, staff *Staff, current map[string]string, opts ...Option) ([]*Diff, error) {
+	// Nothing to manage
+	if len(staff.List) == 0 && !staff.Prune {
+		return nil, nil
+	}
+
+	ctfdStaff := []*api.User{}
+	if !bare {
+		managed := []int{}
+		if err := readState(current, staffState, &managed); err != nil {
+			return nil, err
+		}
+		var err error
+		if ctfdStaff, err = managedStaff(ctx, client, managed, opts...); err != nil {
+			return nil, err
+		}
+	}
+
+	diffs := []*Diff{}
+	for _, member := range staff.List {
+		var ctfdU *api.User
+		if !bare {
+			var err error
+			if ctfdU, err = findUser(ctx, client, member.Email, opts...); err != nil {
+				return nil, err
+			}
+		}
+		if ctfdU == nil {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffAdded,
+				Key:     "staff." + member.Email,
+				Desired: member.Name,
+			})
+			continue
+		}
+		if !slices.ContainsFunc(ctfdStaff, func(u *api.User) bool {
+			return u.ID == ctfdU.ID
+		}) {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffChanged,
+				Key:     "staff." + member.Email,
+				Current: "(unmanaged) " + ctfdU.Name,
+				Desired: member.Name,
+			})
+			continue
+		}
+		diffs = append(diffs, diffStaffMember(ctfdU, member)...)
+	}
+
+	if staff.Prune {
+		for _, ctfdU := range prunedStaff(ctfdStaff, staff.List) {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffRemoved,
+				Key:     "staff." + deref(ctfdU.Email),
+				Current: ctfdU.Name,
+			})
+		}
+	}
+	return diffs, nil
+}
+
+// diffStaffMember compares the attributes of a CTFd user to the staff member ones.
+func diffStaffMember(ctfdU *api.User, member *StaffMember) []*Diff {
+	key := "staff." + member.Email
+	diffs := []*Diff{}
+	for _, f := range []struct {
+		name             string
+		current, desired string
+	}{
+		{"name", ctfdU.Name, member.Name},
+		{"type", deref(ctfdU.Type), "admin"},
+		{"verified", strconv.FormatBool(deref(ctfdU.Verified)), "true"},
+		{"hidden", strconv.FormatBool(deref(ctfdU.Hidden)), "true"},
+	} {
+		if f.current == f.desired {
+			continue
+		}
+		diffs = append(diffs, &Diff{
+			Kind:    DiffChanged,
+			Key:     key + "." + f.name,
+			Current: f.current,
+			Desired: f.desired,
+		})
+	}
+	return diffs
+}
+
+// managedStaff returns the CTFd users of the managed staff accounts that still exist.
+func managedStaff(ctx context.Context, client *Client, managed []int, opts ...Option) ([]*api.User, error) {
+	ctfdStaff := []*api.User{}
+	if len(managed) == 0 {
+		return ctfdStaff, nil
+	}
+	for page := 1; ; page++ {
+		users, err := client.GetUsers(ctx, page, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		if len(users) == 0 {
+			return ctfdStaff, nil
+		}
+		for _, u := range users {
+			if slices.Contains(managed, u.ID) {
+				ctfdStaff = append(ctfdStaff, u)
+			}
+		}
+	}
+}
+
+// prunedStaff returns the managed staff accounts that are not configured anymore.
+func prunedStaff(ctfdStaff []*api.User, list []*StaffMember) []*api.User {
+	pruned := []*api.User{}
+	for _, ctfdU := range ctfdStaff {
+		if !slices.ContainsFunc(list, func(member *StaffMember) bool {
+			return strings.EqualFold(member.Email, deref(ctfdU.Email))
+		}) {
+			pruned = append(pruned, ctfdU)
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,44 @@
+package ctfdsetup
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// stateKeyPrefix prefixes the CTFd configs ctfd-setup stores its own state in.
+// This state (e.g. the resources it manages) enables telling apart what
+// ctfd-setup created from what was created by other means.
+const stateKeyPrefix = "ctfd_setup_"
+
+// readState decodes the state stored under the given key into v, if any.
+func readState(current map[string]string, key string, v any) error {
+	raw, ok := current[stateKeyPrefix+key]
+	if !ok || raw == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return errors.Wrapf(err, "decoding ctfd-setup state %s", key)
+	}
+	return nil
+}
+
+// hasState returns whether a state was stored under the given key.
+func hasState(current map[string]string, key string) bool {
+	return current[stateKeyPrefix+key] != ""
+}
+
+// writeState encodes and stores v under the given key.
+func writeState(ctx context.Context, client *Client, key string, v any, opts ...Option) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "encoding ctfd-setup state %s", key)
+	}
+	if err := client.PatchConfigsValues(ctx, map[string]any{
+		stateKeyPrefix + key: string(b),
+	}, opts...); err != nil {
+		return &ErrClient{err: err}
+	}
+	return nil
+}
@@ -0,0 +1,459 @@
+package ctfdsetup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// teamsCSVColumns are the columns a teams CSV file can define.
+var teamsCSVColumns = []string{"name", "email", "password", "affiliation", "country", "bracket", "members", "captain"}
+
+// validateTeams checks the teams can be loaded, their names are unique and the
+// CTFd is in teams mode.
+func validateTeams(teams *Teams, mode string) error {
+	if teams == nil {
+		return nil
+	}
+	list, err := teams.all()
+	if err != nil {
+		return err
+	}
+	if len(list) != 0 && mode != "teams" {
+		return fmt.Errorf("teams can only be defined in teams mode, got %s", mode)
+	}
+
+	var merr error
+	names := map[string]struct{}{}
+	for _, team := range list {
+		if _, ok := names[team.Name]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("team %s: duplicated name", team.Name))
+		}
+		names[team.Name] = struct{}{}
+
+		if team.Captain != nil && !slices.Contains(team.Members, *team.Captain) {
+			merr = multierr.Append(merr, fmt.Errorf("team %s: captain %s is not a member", team.Name, *team.Captain))
+		}
+	}
+	return merr
+}
+
+// all returns the teams of the list, followed by the ones of the CSV file.
+func (teams *Teams) all() ([]*Team, error) {
+	list := slices.Clone(teams.List)
+	if teams.CSV != nil && len(teams.CSV.Content) != 0 {
+		csvTeams, err := teamsFromCSV(teams.CSV.Content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "importing teams from %s", teams.CSV.Name)
+		}
+		list = append(list, csvTeams...)
+	}
+	return list, nil
+}
+
+// teamsFromCSV parses teams from a CSV content, which first row names the columns.
+func teamsFromCSV(content []byte) ([]*Team, error) {
+	rows, err := readCSV(content, teamsCSVColumns)
+	if err != nil {
+		return nil, err
+	}
+	teams := []*Team{}
+	for i, row := range rows {
+		team := &Team{
+			Name:        row["name"],
+			Email:       optional(row["email"]),
+			Affiliation: optional(row["affiliation"]),
+			Country:     optional(row["country"]),
+			Bracket:     optional(row["bracket"]),
+			Captain:     optional(row["captain"]),
+		}
+		if team.Name == "" {
+			return nil, fmt.Errorf("row %d: missing team name", i+1)
+		}
+		if v := row["password"]; v != "" {
+			team.Password = &FromEnv{Content: v}
+		}
+		for _, m := range strings.Split(row["members"], ";") {
+			if m = strings.TrimSpace(m); m != "" {
+				team.Members = append(team.Members, m)
+			}
+		}
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+// readCSV parses a CSV content which first row names the columns, among the
+// given ones. Each row is returned as a map of its values by column.
+func readCSV(content []byte, columns []string) ([]map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading header")
+	}
+	for _, col := range header {
+		if !slices.Contains(columns, col) {
+			return nil, fmt.Errorf("unknown column %s, expected one of %s", col, strings.Join(columns, ", "))
+		}
+	}
+	if !slices.Contains(header, columns[0]) {
+		return nil, fmt.Errorf("missing column %s", columns[0])
+	}
+
+	rows := []map[string]string{}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := map[string]string{}
+		for i, col := range header {
+			row[col] = rec[i]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// optional returns a pointer to the value, or nil if empty.
+func optional(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// updateTeams creates the configured teams that do not exist yet, and updates
+// the attributes and members of the others, matched by name.
+// The passwords generated for the created teams are appended to the passwords file.
+// Members are never removed, as CTFd would drop their submissions along.
+func updateTeams(ctx context.Context, client *Client, teams *Teams, opts ...Option) error {
+	list, err := teams.all()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	brackets, err := bracketIDs(ctx, client, teamsBrackets(list), opts...)
+	if err != nil {
+		return err
+	}
+
+	var merr error
+	generated := [][]string{}
+	for _, team := range list {
+		ctfdT, err := findTeam(ctx, client, team.Name, opts...)
+		if err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "getting team %s", team.Name))
+			continue
+		}
+		members, err := findMembers(ctx, client, team.Members, opts...)
+		if err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "team %s", team.Name))
+			continue
+		}
+		if team.Bracket != nil && bracketID(brackets, team.Bracket) == nil {
+			merr = multierr.Append(merr, fmt.Errorf("team %s: bracket %s does not exist", team.Name, *team.Bracket))
+			continue
+		}
+
+		if ctfdT == nil {
+			// CREATE
+			password := ""
+			if team.Password != nil {
+				password = team.Password.Content
+			} else {
+				password = rand.Text()
+			}
+
+			Log().Info(ctx, "creating team",
+				zap.String("name", team.Name),
+			)
+			ctfdT, err = client.PostTeams(ctx, &postTeamsParams{
+				Name:        team.Name,
+				Email:       deref(team.Email),
+				Password:    password,
+				Affiliation: team.Affiliation,
+				Country:     team.Country,
+				Fields:      []api.Field{},
+				BracketID:   bracketID(brackets, team.Bracket),
+			}, opts...)
+			if err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "creating team %s", team.Name))
+				continue
+			}
+			if team.Password == nil {
+				generated = append(generated, []string{team.Name, password})
+			}
+		}
+
+		if err := updateTeam(ctx, client, ctfdT, team, brackets, members, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "updating team %s", team.Name))
+		}
+	}
+
+	// Write the generated passwords, even on partial failure as the teams got created
+	if len(generated) != 0 {
+		if err := appendCredentials(teams.PasswordsFile, []string{"name", "password"}, generated); err != nil {
+			merr = multierr.Append(merr, errors.Wrap(err, "writing teams passwords"))
+		}
+	}
+	return merr
+}
+
+// updateTeam adds the missing members to the team, then updates its attributes.
+// The captain is updated last, as it must be a member.
+func updateTeam(ctx context.Context, client *Client, ctfdT *api.Team, team *Team, brackets map[string]int, members map[string]int, opts ...Option) error {
+	current, err := client.GetTeamMembers(ctx, ctfdT.ID, opts...)
+	if err != nil {
+		return &ErrClient{err: err}
+	}
+	for _, name := range team.Members {
+		if slices.Contains(current, members[name]) {
+			continue
+		}
+		Log().Info(ctx, "adding team member",
+			zap.String("team", team.Name),
+			zap.String("user", name),
+		)
+		if _, err := client.PostTeamMembers(ctx, ctfdT.ID, &api.PostTeamsMembersParams{
+			UserID: members[name],
+		}, opts...); err != nil {
+			return errors.Wrapf(err, "adding member %s", name)
+		}
+	}
+
+	if len(diffTeam(ctfdT, team, brackets, members)) == 0 {
+		return nil
+	}
+	var captainID *int
+	if team.Captain != nil {
+		captainID = ptr(members[*team.Captain])
+	}
+	_, err = client.PatchTeam(ctx, ctfdT.ID, &api.PatchTeamsParams{
+		CaptainID:   captainID,
+		Email:       team.Email,
+		Affiliation: team.Affiliation,
+		Country:     team.Country,
+		Fields:      []api.Field{},
+		BracketID:   bracketID(brackets, team.Bracket),
+	}, opts...)
+	return err
+}
+
+// diffTeams compares the CTFd teams to the configured ones, per field.
+// Passwords can't be read thus are not compared.
+func diffTeams(ctx context.Context, client *Client, bare bool, teams *Teams, opts ...Option) ([]*Diff, error) {
+	list, err := teams.all()
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	brackets := map[string]int{}
+	if !bare {
+		if brackets, err = bracketIDs(ctx, client, teamsBrackets(list), opts...); err != nil {
+			return nil, err
+		}
+	}
+
+	diffs := []*Diff{}
+	for _, team := range list {
+		key := "teams." + team.Name
+
+		var ctfdT *api.Team
+		if !bare {
+			if ctfdT, err = findTeam(ctx, client, team.Name, opts...); err != nil {
+				return nil, err
+			}
+		}
+		if ctfdT == nil {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffAdded,
+				Key:     key,
+				Desired: team.Name,
+			})
+			continue
+		}
+
+		members, err := findMembers(ctx, client, team.Members, opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "team %s", team.Name)
+		}
+		current, err := client.GetTeamMembers(ctx, ctfdT.ID, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		for _, name := range team.Members {
+			if !slices.Contains(current, members[name]) {
+				diffs = append(diffs, &Diff{
+					Kind:    DiffAdded,
+					Key:     key + ".members",
+					Desired: name,
+				})
+			}
+		}
+		diffs = append(diffs, diffTeam(ctfdT, team, brackets, members)...)
+	}
+	return diffs, nil
+}
+
+// diffTeam compares the attributes of a CTFd team to the configured ones.
+// Attributes that are not configured are left untouched thus not compared.
+func diffTeam(ctfdT *api.Team, team *Team, brackets map[string]int, members map[string]int) []*Diff {
+	key := "teams." + team.Name
+	diffs := []*Diff{}
+	for _, f := range []struct {
+		name             string
+		current, desired *string
+	}{
+		{"email", ctfdT.Email, team.Email},
+		{"affiliation", ctfdT.Affiliation, team.Affiliation},
+		{"country", ctfdT.Country, team.Country},
+		{"bracket", intString(ctfdT.BracketID), bracketID(brackets, team.Bracket)},
+		{"captain", intString(ctfdT.CaptainID), memberID(members, team.Captain)},
+	} {
+		if f.desired == nil || deref(f.current) == *f.desired {
+			continue
+		}
+		diffs = append(diffs, &Diff{
+			Kind:    DiffChanged,
+			Key:     key + "." + f.name,
+			Current: deref(f.current),
+			Desired: *f.desired,
+		})
+	}
+	return diffs
+}
+
+// findTeam returns the CTFd team with the given name, or nil if it does not exist.
+func findTeam(ctx context.Context, client *Client, name string, opts ...Option) (*api.Team, error) {
+	teams, err := client.SearchTeams(ctx, "name", name, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	for _, t := range teams {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// findMembers returns the CTFd users IDs of the given names. All of them must exist.
+func findMembers(ctx context.Context, client *Client, names []string, opts ...Option) (map[string]int, error) {
+	ids := map[string]int{}
+	for _, name := range names {
+		users, err := client.SearchUsers(ctx, "name", name, opts...)
+		if err != nil {
+			return nil, &ErrClient{err: err}
+		}
+		idx := slices.IndexFunc(users, func(u *api.User) bool {
+			return u.Name == name
+		})
+		if idx == -1 {
+			return nil, fmt.Errorf("member %s does not exist", name)
+		}
+		ids[name] = users[idx].ID
+	}
+	return ids, nil
+}
+
+func teamsBrackets(teams []*Team) []*string {
+	brackets := []*string{}
+	for _, team := range teams {
+		brackets = append(brackets, team.Bracket)
+	}
+	return brackets
+}
+
+// bracketIDs returns the CTFd brackets IDs by name, if any of the given brackets is set.
+func bracketIDs(ctx context.Context, client *Client, brackets []*string, opts ...Option) (map[string]int, error) {
+	ids := map[string]int{}
+	if !slices.ContainsFunc(brackets, func(bk *string) bool {
+		return bk != nil
+	}) {
+		return ids, nil
+	}
+	bks, err := client.GetBrackets(ctx, nil, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	for _, bk := range bks {
+		ids[bk.Name] = bk.ID
+	}
+	return ids, nil
+}
+
+// bracketID returns the CTFd bracket ID of the given name as CTFd expects it,
+// or nil if not set or unknown.
+func bracketID(brackets map[string]int, name *string) *string {
+	if name == nil {
+		return nil
+	}
+	id, ok := brackets[*name]
+	if !ok {
+		return nil
+	}
+	return ptr(strconv.Itoa(id))
+}
+
+func memberID(members map[string]int, name *string) *string {
+	if name == nil {
+		return nil
+	}
+	return ptr(strconv.Itoa(members[*name]))
+}
+
+func intString(i *int) *string {
+	if i == nil {
+		return nil
+	}
+	return ptr(strconv.Itoa(*i))
+}
+
+// appendCredentials appends the credentials to a local CSV file, readable only
+// by its owner. The header is written when the file is created.
+func appendCredentials(path string, header []string, creds [][]string) error {
+	_, err := os.Stat(path)
+	exists := err == nil
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	w := csv.NewWriter(f)
+	if !exists {
+		if err := w.Write(header); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteAll(creds); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,98 @@
+package ctfdsetup
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// TokenOutputFile writes the bootstrapped token to a file, that later runs reuse.
+	TokenOutputFile = "file"
+	// TokenOutputStdout writes the bootstrapped token to the standard output.
+	TokenOutputStdout = "stdout"
+	// TokenOutputGitHub writes the bootstrapped token to the GitHub Actions step outputs.
+	TokenOutputGitHub = "github"
+
+	// TokenFile is the default file the bootstrapped token is written to.
+	TokenFile = ".ctfd-token"
+
+	// tokenGitHubOutput is the name of the GitHub Actions step output of the token.
+	tokenGitHubOutput = "api_key"
+)
+
+// bootstrapToken creates an API token with the administrator session opened by
+// the bare setup, then writes it to the configured output.
+func bootstrapToken(ctx context.Context, client *Client, token *Token, opts ...Option) error {
+	if token == nil || token.Output == "" {
+		return nil
+	}
+
+	Log().Info(ctx, "bootstrapping API token",
+		zap.String("output", token.Output),
+	)
+	t, err := client.PostTokens(ctx, &api.PostTokensParams{
+		Description: token.Description,
+		Expiration:  token.Expiration,
+	}, opts...)
+	if err != nil {
+		return &ErrClient{err: err}
+	}
+	if t.Value == nil {
+		return errors.New("CTFd returned no token value")
+	}
+	return writeToken(token, *t.Value)
+}
+
+// writeToken writes the token value to the configured output.
+func writeToken(token *Token, value string) error {
+	switch token.Output {
+	case TokenOutputFile:
+		return os.WriteFile(orDefault(token.File, TokenFile), []byte(value+"\n"), 0600)
+
+	case TokenOutputStdout:
+		_, err := fmt.Fprintln(os.Stdout, value)
+		return err
+
+	case TokenOutputGitHub:
+		out := os.Getenv("GITHUB_OUTPUT")
+		if out == "" {
+			return errors.New("GITHUB_OUTPUT is not defined, are you running in GitHub Actions?")
+		}
+		// Mask the token in the workflow logs, before any step can print it
+		if _, err := fmt.Fprintf(os.Stdout, "::add-mask::%s\n", value); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return errors.Wrap(err, "opening GitHub Actions outputs")
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		_, err = fmt.Fprintf(f, "%s=%s\n", tokenGitHubOutput, value)
+		return err
+	}
+	return fmt.Errorf("unsupported token output %s", token.Output)
+}
+
+// reuseToken returns the token a previous run wrote to the token file, if any.
+func reuseToken(token *Token) (string, error) {
+	if token == nil || token.Output != TokenOutputFile {
+		return "", nil
+	}
+	b, err := os.ReadFile(orDefault(token.File, TokenFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "reading token file")
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,55 @@
+package ctfdsetup_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ctfdsetup "github.com/ctfer-io/ctfd-setup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_U_TokenFile(t *testing.T) {
+	t.Parallel()
+
+	token := &ctfdsetup.Token{
+		Output: ctfdsetup.TokenOutputFile,
+		File:   filepath.Join(t.TempDir(), ".ctfd-token"),
+	}
+
+	// Nothing to reuse before the token is bootstrapped
+	value, err := ctfdsetup.ReuseToken(token)
+	require.NoError(t, err)
+	assert.Empty(t, value)
+
+	require.NoError(t, ctfdsetup.WriteToken(token, "ctfd_0123456789"))
+
+	info, err := os.Stat(token.File)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	value, err = ctfdsetup.ReuseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "ctfd_0123456789", value)
+}
+
+func Test_U_TokenGitHub(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "output")
+	require.NoError(t, os.WriteFile(out, []byte("previous=step\n"), 0600))
+	t.Setenv("GITHUB_OUTPUT", out)
+
+	token := &ctfdsetup.Token{
+		Output: ctfdsetup.TokenOutputGitHub,
+	}
+	require.NoError(t, ctfdsetup.WriteToken(token, "ctfd_0123456789"))
+
+	b, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "previous=step\napi_key=ctfd_0123456789\n", string(b))
+
+	// Only tokens written to a file are reused
+	value, err := ctfdsetup.ReuseToken(token)
+	require.NoError(t, err)
+	assert.Empty(t, value)
+}
@@ -0,0 +1,127 @@
+package ctfdsetup
+
+import (
+	"context"
+	"slices"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	// UploadsKeep keeps on CTFd the files removed from the uploads.
+	UploadsKeep = "keep"
+	// UploadsPrune deletes from CTFd the files removed from the uploads.
+	UploadsPrune = "prune"
+
+	// uploadsState is the state key of the locations managed by ctfd-setup.
+	uploadsState = "uploads"
+)
+
+// updateUploads pushes the uploads that changed, then prunes the managed ones
+// that were removed if the policy says so.
+// Only the locations uploaded by ctfd-setup are managed, such that files
+// uploaded by other means (e.g. challenges files) are never touched.
+func updateUploads(ctx context.Context, client *Client, conf *Config, current map[string]string, opts ...Option) error {
+	managed := []string{}
+	if err := readState(current, uploadsState, &managed); err != nil {
+		return err
+	}
+	manifest := slices.Clone(managed)
+
+	var merr error
+	for _, f := range conf.Uploads {
+		// Compute file hash
+		x := sha1sum(f.File.Content)
+
+		// Get the file from CTFd
+		fs, err := client.GetFiles(ctx, &api.GetFilesParams{
+			Location: &f.Location,
+		}, opts...)
+		if err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "getting file at %s", f.Location))
+			continue
+		}
+
+		// Check if need re-push
+		if len(fs) != 0 && fs[0].SHA1sum == x {
+			if !slices.Contains(manifest, f.Location) {
+				manifest = append(manifest, f.Location)
+			}
+			continue
+		}
+
+		// Else push it (or update it)
+		logger.Debug(ctx, "uploading file",
+			zap.String("location", f.Location),
+		)
+		if _, err := client.PostFiles(ctx, &api.PostFilesParams{
+			Files: []*api.InputFile{
+				{Name: f.File.Name, Content: f.File.Content},
+			},
+			Location: &f.Location,
+		}, opts...); err != nil {
+			merr = multierr.Append(merr, err)
+			continue
+		}
+		if !slices.Contains(manifest, f.Location) {
+			manifest = append(manifest, f.Location)
+		}
+	}
+
+	// Prune the managed files that are no longer configured
+	if conf.UploadsPolicy == UploadsPrune {
+		for _, loc := range prunedUploads(manifest, conf.Uploads) {
+			fs, err := client.GetFiles(ctx, &api.GetFilesParams{
+				Location: &loc,
+			}, opts...)
+			if err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "getting file at %s", loc))
+				continue
+			}
+
+			logger.Info(ctx, "pruning file",
+				zap.String("location", loc),
+			)
+			var ferr error
+			for _, f := range fs {
+				if err := client.DeleteFile(ctx, f.ID, opts...); err != nil {
+					ferr = multierr.Append(ferr, errors.Wrapf(err, "deleting file at %s", loc))
+				}
+			}
+			if ferr != nil {
+				merr = multierr.Append(merr, ferr)
+				continue
+			}
+			manifest = slices.DeleteFunc(manifest, func(l string) bool {
+				return l == loc
+			})
+		}
+	}
+
+	// Save the manifest if it changed, even on partial failure to keep track
+	// of what got uploaded
+	slices.Sort(manifest)
+	slices.Sort(managed)
+	if !slices.Equal(manifest, managed) {
+		if err := writeState(ctx, client, uploadsState, manifest, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrap(err, "saving uploads manifest"))
+		}
+	}
+	return merr
+}
+
+// prunedUploads returns the managed locations that are not configured anymore.
+func prunedUploads(managed []string, uploads []*Upload) []string {
+	pruned := []string{}
+	for _, loc := range managed {
+		if !slices.ContainsFunc(uploads, func(up *Upload) bool {
+			return up.Location == loc
+		}) {
+			pruned = append(pruned, loc)
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,337 @@
+package ctfdsetup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ctfer-io/go-ctfd/api"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// usersCSVColumns are the columns a users CSV file can define.
+var usersCSVColumns = []string{"email", "name", "password", "type", "verified", "hidden", "banned", "affiliation", "country", "bracket"}
+
+// validateUsers checks the users can be loaded, their emails are unique, none of
+// them is the administrator and brackets are only used in users mode.
+func validateUsers(users *Users, mode string, admin Admin) error {
+	if users == nil {
+		return nil
+	}
+	list, err := users.all()
+	if err != nil {
+		return err
+	}
+
+	var merr error
+	emails := map[string]struct{}{}
+	for _, user := range list {
+		email := strings.ToLower(user.Email)
+		if _, ok := emails[email]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("user %s: duplicated email", user.Email))
+		}
+		emails[email] = struct{}{}
+
+		if admin.Email.Content != "" && strings.EqualFold(user.Email, admin.Email.Content) {
+			merr = multierr.Append(merr, fmt.Errorf("user %s: is the administrator", user.Email))
+		}
+		if user.Name == "" {
+			merr = multierr.Append(merr, fmt.Errorf("user %s: missing name", user.Email))
+		}
+		if user.Type != "" && user.Type != "user" && user.Type != "admin" {
+			merr = multierr.Append(merr, fmt.Errorf("user %s: invalid type %s", user.Email, user.Type))
+		}
+		if user.Bracket != nil && mode != "users" {
+			merr = multierr.Append(merr, fmt.Errorf("user %s: brackets of users are only supported in users mode", user.Email))
+		}
+	}
+	return merr
+}
+
+// all returns the users of the list, followed by the ones of the CSV then JSON files.
+func (users *Users) all() ([]*User, error) {
+	list := make([]*User, 0, len(users.List))
+	list = append(list, users.List...)
+	if users.CSV != nil && len(users.CSV.Content) != 0 {
+		csvUsers, err := usersFromCSV(users.CSV.Content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "importing users from %s", users.CSV.Name)
+		}
+		list = append(list, csvUsers...)
+	}
+	if users.JSON != nil && len(users.JSON.Content) != 0 {
+		// JSON is valid YAML, thus passwords can be defined as in the configuration
+		jsonUsers := []*User{}
+		dec := yaml.NewDecoder(bytes.NewReader(users.JSON.Content))
+		dec.KnownFields(true)
+		if err := dec.Decode(&jsonUsers); err != nil {
+			return nil, errors.Wrapf(err, "importing users from %s", users.JSON.Name)
+		}
+		list = append(list, jsonUsers...)
+	}
+	return list, nil
+}
+
+// usersFromCSV parses users from a CSV content, which first row names the columns.
+func usersFromCSV(content []byte) ([]*User, error) {
+	rows, err := readCSV(content, usersCSVColumns)
+	if err != nil {
+		return nil, err
+	}
+	users := []*User{}
+	for i, row := range rows {
+		user := &User{
+			Name:        row["name"],
+			Email:       row["email"],
+			Type:        row["type"],
+			Affiliation: optional(row["affiliation"]),
+			Country:     optional(row["country"]),
+			Bracket:     optional(row["bracket"]),
+		}
+		if user.Email == "" {
+			return nil, fmt.Errorf("row %d: missing user email", i+1)
+		}
+		if v := row["password"]; v != "" {
+			user.Password = &FromEnv{Content: v}
+		}
+		for col, dst := range map[string]*bool{
+			"verified": &user.Verified,
+			"hidden":   &user.Hidden,
+			"banned":   &user.Banned,
+		} {
+			if row[col] == "" {
+				continue
+			}
+			b, err := strconv.ParseBool(row[col])
+			if err != nil {
+				return nil, errors.Wrapf(err, "row %d: invalid %s value", i+1, col)
+			}
+			*dst = b
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// usersReport sums up what provisioning users did, by email.
+type usersReport struct {
+	Created, Updated, Skipped []string
+}
+
+// updateUsers creates the configured users that do not exist yet, and updates
+// the others, matched by email. Passwords are only set on creation: the ones
+// generated are appended to the passwords file.
+// What got created, updated and skipped (as up to date) is reported in logs.
+func updateUsers(ctx context.Context, client *Client, users *Users, opts ...Option) error {
+	list, err := users.all()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	brackets, err := bracketIDs(ctx, client, usersBrackets(list), opts...)
+	if err != nil {
+		return err
+	}
+
+	var merr error
+	report := &usersReport{}
+	generated := [][]string{}
+	for _, user := range list {
+		if user.Bracket != nil && bracketID(brackets, user.Bracket) == nil {
+			merr = multierr.Append(merr, fmt.Errorf("user %s: bracket %s does not exist", user.Email, *user.Bracket))
+			continue
+		}
+		ctfdU, err := findUser(ctx, client, user.Email, opts...)
+		if err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "getting user %s", user.Email))
+			continue
+		}
+
+		// CREATE
+		if ctfdU == nil {
+			password := ""
+			if user.Password != nil {
+				password = user.Password.Content
+			} else {
+				password = rand.Text()
+			}
+
+			if _, err := client.PostUsers(ctx, &api.PostUsersParams{
+				Name:        user.Name,
+				Email:       user.Email,
+				Password:    password,
+				Affiliation: user.Affiliation,
+				Country:     user.Country,
+				Type:        userType(user),
+				Verified:    user.Verified,
+				Hidden:      user.Hidden,
+				Banned:      user.Banned,
+				Fields:      []api.Field{},
+				BracketID:   bracketID(brackets, user.Bracket),
+			}, opts...); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "creating user %s", user.Email))
+				continue
+			}
+			if user.Password == nil {
+				generated = append(generated, []string{user.Email, password})
+			}
+			report.Created = append(report.Created, user.Email)
+			continue
+		}
+
+		// SKIP
+		if len(diffUser(ctfdU, user, brackets)) == 0 {
+			report.Skipped = append(report.Skipped, user.Email)
+			continue
+		}
+
+		// UPDATE
+		if _, err := client.PatchUser(ctx, ctfdU.ID, &api.PatchUsersParams{
+			Name:        user.Name,
+			Email:       deref(ctfdU.Email), // keep the case it was registered with
+			Affiliation: user.Affiliation,
+			Country:     user.Country,
+			Type:        ptr(userType(user)),
+			Verified:    &user.Verified,
+			Hidden:      &user.Hidden,
+			Banned:      &user.Banned,
+			Fields:      []api.Field{},
+			BracketID:   bracketID(brackets, user.Bracket),
+		}, opts...); err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "updating user %s", user.Email))
+			continue
+		}
+		report.Updated = append(report.Updated, user.Email)
+	}
+
+	Log().Info(ctx, "users provisioned",
+		zap.Strings("created", report.Created),
+		zap.Strings("updated", report.Updated),
+		zap.Strings("skipped", report.Skipped),
+	)
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("ctfd.users.created", len(report.Created)),
+		attribute.Int("ctfd.users.updated", len(report.Updated)),
+		attribute.Int("ctfd.users.skipped", len(report.Skipped)),
+	)
+
+	// Write the generated passwords, even on partial failure as the users got created
+	if len(generated) != 0 {
+		if err := appendCredentials(users.PasswordsFile, []string{"email", "password"}, generated); err != nil {
+			merr = multierr.Append(merr, errors.Wrap(err, "writing users passwords"))
+		}
+	}
+	return merr
+}
+
+// diffUsers compares the CTFd users to the configured ones, per field.
+// Passwords can't be read thus are not compared.
+func diffUsers(ctx context.Context, client *Client, bare bool, users *Users, opts ...Option) ([]*Diff, error) {
+	list, err := users.all()
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	brackets := map[string]int{}
+	if !bare {
+		if brackets, err = bracketIDs(ctx, client, usersBrackets(list), opts...); err != nil {
+			return nil, err
+		}
+	}
+
+	diffs := []*Diff{}
+	for _, user := range list {
+		var ctfdU *api.User
+		if !bare {
+			if ctfdU, err = findUser(ctx, client, user.Email, opts...); err != nil {
+				return nil, err
+			}
+		}
+		if ctfdU == nil {
+			diffs = append(diffs, &Diff{
+				Kind:    DiffAdded,
+				Key:     "users." + user.Email,
+				Desired: user.Name,
+			})
+			continue
+		}
+		diffs = append(diffs, diffUser(ctfdU, user, brackets)...)
+	}
+	return diffs, nil
+}
+
+// diffUser compares the attributes of a CTFd user to the configured ones.
+// Optional attributes that are not configured are left untouched thus not compared.
+func diffUser(ctfdU *api.User, user *User, brackets map[string]int) []*Diff {
+	key := "users." + user.Email
+	diffs := []*Diff{}
+	for _, f := range []struct {
+		name             string
+		current, desired *string
+	}{
+		{"name", &ctfdU.Name, &user.Name},
+		{"type", ctfdU.Type, ptr(userType(user))},
+		{"verified", boolString(ctfdU.Verified), ptr(strconv.FormatBool(user.Verified))},
+		{"hidden", boolString(ctfdU.Hidden), ptr(strconv.FormatBool(user.Hidden))},
+		{"banned", boolString(ctfdU.Banned), ptr(strconv.FormatBool(user.Banned))},
+		{"affiliation", ctfdU.Affiliation, user.Affiliation},
+		{"country", ctfdU.Country, user.Country},
+		{"bracket", intString(ctfdU.BracketID), bracketID(brackets, user.Bracket)},
+	} {
+		if f.desired == nil || deref(f.current) == *f.desired {
+			continue
+		}
+		diffs = append(diffs, &Diff{
+			Kind:    DiffChanged,
+			Key:     key + "." + f.name,
+			Current: deref(f.current),
+			Desired: *f.desired,
+		})
+	}
+	return diffs
+}
+
+// findUser returns the CTFd user with the given email, or nil if it does not exist.
+// Emails are compared case-insensitively.
+func findUser(ctx context.Context, client *Client, email string, opts ...Option) (*api.User, error) {
+	users, err := client.SearchUsers(ctx, "email", email, opts...)
+	if err != nil {
+		return nil, &ErrClient{err: err}
+	}
+	for _, u := range users {
+		if strings.EqualFold(deref(u.Email), email) {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func usersBrackets(users []*User) []*string {
+	brackets := []*string{}
+	for _, user := range users {
+		brackets = append(brackets, user.Bracket)
+	}
+	return brackets
+}
+
+func userType(user *User) string {
+	return orDefault(user.Type, "user")
+}
+
+func boolString(b *bool) *string {
+	return ptr(strconv.FormatBool(deref(b)))
+}